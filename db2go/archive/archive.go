@@ -0,0 +1,663 @@
+/*
+归档，把表中满足条件的行按主键的顺序分批移到归档表或者文件，每批一个事务，
+先写归档表（或者文件）再删除，批之间可以暂停，减少对线上的影响。
+进度保存在状态文件中，中断后再次执行从上次的主键继续。
+被子表引用的行，默认不归档，也可以连同子表引用的行一起归档。
+*/
+package archive
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"github.com/qq51529210/db/db2go/dump"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBatch  = 1000       // 默认每批的行数
+	DefaultSuffix = "_archive" // 默认归档表名的后缀
+	ChildRestrict = "restrict" // 被子表引用的行不归档
+	ChildCascade  = "cascade"  // 连同子表引用的行一起归档
+	maxDepth      = 16         // 级联的最大层数
+)
+
+var (
+	errNoDB = errors.New("schema has no database connection")
+	// 一条语句最多的参数
+	maxParams = map[string]int{
+		db2go.MYSQL:    65535,
+		db2go.POSTGRES: 65535,
+		db2go.SQLITE:   999,
+	}
+	// 锁定选中的行
+	lockSuffix = map[string]string{
+		db2go.MYSQL:    " for update",
+		db2go.POSTGRES: " for update",
+	}
+	// 按原表创建归档表，参数是归档表名，原表名
+	createLikeFormat = map[string]string{
+		db2go.MYSQL:    "create table if not exists %s like %s",
+		db2go.POSTGRES: "create table if not exists %s (like %s including all)",
+	}
+)
+
+// 归档的进度，也是状态文件的内容
+type State struct {
+	Table   string        `json:"table"`
+	Where   string        `json:"where"`
+	LastKey []interface{} `json:"lastKey,omitempty"` // 已处理的最大的主键
+	Rows    int64         `json:"rows"`              // 已归档的行数，不包括级联的子表
+	Skipped int64         `json:"skipped"`           // 被子表引用而没有归档的行数
+	Done    bool          `json:"done"`
+}
+
+func (s *State) String() string {
+	if s.Done {
+		return fmt.Sprintf("%s: %d rows archived, %d skipped, done", s.Table, s.Rows, s.Skipped)
+	}
+	return fmt.Sprintf("%s: %d rows archived, %d skipped", s.Table, s.Rows, s.Skipped)
+}
+
+// 子表的一个外键
+type childKey struct {
+	table  *db2go.Table
+	column *db2go.Column // 子表的列
+	ref    *db2go.Column // 被引用的列
+}
+
+// 归档器
+type Archiver struct {
+	schema    *db2go.Schema
+	db        *sql.DB
+	dbType    string
+	table     *db2go.Table
+	where     string
+	batch     int
+	sleep     time.Duration
+	child     string
+	suffix    string    // 归档表名的后缀，空表示不写归档表
+	writer    io.Writer // 写json lines，nil表示不写文件
+	stateFile string
+	progress  func(*State)
+}
+
+// 归档schema中table满足where的行，table必须有主键
+func NewArchiver(schema *db2go.Schema, table, where string) (*Archiver, error) {
+	t := schema.GetTable(table)
+	if t == nil {
+		return nil, fmt.Errorf("table '%s' not found", table)
+	}
+	if pk, _ := t.PrimaryKeyColumns(); len(pk) < 1 {
+		return nil, fmt.Errorf("table '%s' has no primary key", table)
+	}
+	if strings.TrimSpace(where) == "" {
+		return nil, fmt.Errorf("empty where")
+	}
+	a := new(Archiver)
+	a.schema = schema
+	a.db = schema.DB()
+	a.dbType = schema.DBType()
+	a.table = t
+	a.where = where
+	a.batch = DefaultBatch
+	a.child = ChildRestrict
+	a.progress = func(*State) {}
+	return a, nil
+}
+
+// 每批的行数
+func (a *Archiver) SetBatch(rows int) {
+	if rows < 1 {
+		rows = 1
+	}
+	a.batch = rows
+}
+
+// 每批之后暂停的时间
+func (a *Archiver) SetSleep(d time.Duration) {
+	a.sleep = d
+}
+
+// 被子表引用的行的处理方式，ChildRestrict或者ChildCascade
+func (a *Archiver) SetChildren(mode string) error {
+	switch mode {
+	case ChildRestrict, ChildCascade:
+		a.child = mode
+		return nil
+	default:
+		return fmt.Errorf("unknown children mode '%s'", mode)
+	}
+}
+
+// 写到同一个数据库的归档表，表名是原表名加上suffix，不存在时按原表创建
+func (a *Archiver) SetArchiveSuffix(suffix string) {
+	a.suffix = suffix
+}
+
+// 以json lines的格式写到w，可以用dump.Restore导入，
+// 写完再提交删除，中断时w中可能有没删除的行，再次执行时会重复
+func (a *Archiver) SetWriter(w io.Writer) {
+	a.writer = w
+}
+
+// 状态文件，每批之后保存，存在时从上次的主键继续
+func (a *Archiver) SetStateFile(file string) {
+	a.stateFile = file
+}
+
+// 每批之后回调
+func (a *Archiver) SetProgress(fn func(*State)) {
+	a.progress = fn
+}
+
+// 执行归档，返回最后的状态
+func (a *Archiver) Run() (*State, error) {
+	if a.db == nil {
+		return nil, errNoDB
+	}
+	if a.suffix == "" && a.writer == nil {
+		return nil, errors.New("no archive table or writer")
+	}
+	state, err := a.loadState()
+	if err != nil {
+		return nil, err
+	}
+	if state.Done {
+		return state, nil
+	}
+	if a.suffix != "" {
+		err = a.createTables(a.table, 0)
+		if err != nil {
+			return state, err
+		}
+	}
+	for {
+		n, err := a.runBatch(state)
+		if err != nil {
+			return state, err
+		}
+		if n < a.batch {
+			state.Done = true
+		}
+		err = a.saveState(state)
+		if err != nil {
+			return state, err
+		}
+		a.progress(state)
+		if state.Done {
+			return state, nil
+		}
+		if a.sleep > 0 {
+			time.Sleep(a.sleep)
+		}
+	}
+}
+
+// 读取状态文件，条件不同时报错，避免用错文件
+func (a *Archiver) loadState() (*State, error) {
+	state := &State{Table: a.table.Name(), Where: a.where}
+	if a.stateFile == "" {
+		return state, nil
+	}
+	data, err := ioutil.ReadFile(a.stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	saved := new(State)
+	err = dec.Decode(saved)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", a.stateFile, err)
+	}
+	if saved.Table != state.Table || saved.Where != state.Where {
+		return nil, fmt.Errorf("%s: state of table '%s' where '%s'", a.stateFile, saved.Table, saved.Where)
+	}
+	for i, v := range saved.LastKey {
+		if n, ok := v.(json.Number); ok {
+			saved.LastKey[i] = n.String()
+		}
+	}
+	return saved, nil
+}
+
+// 先写临时文件再改名，中断时不会留下不完整的文件
+func (a *Archiver) saveState(state *State) error {
+	if a.stateFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(a.stateFile), "."+filepath.Base(a.stateFile)+".tmp")
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, a.stateFile)
+}
+
+// 被引用的列是column的子表
+func (a *Archiver) children(t *db2go.Table) []*childKey {
+	var keys []*childKey
+	for _, ct := range a.schema.Tables() {
+		for _, c := range ct.Columns() {
+			ft := c.ForeignTable()
+			if ft != nil && ft.Table() == t && ft.Column() != nil {
+				keys = append(keys, &childKey{table: ct, column: c, ref: ft.Column()})
+			}
+		}
+	}
+	return keys
+}
+
+// 创建归档表，级联时包括子表的
+func (a *Archiver) createTables(t *db2go.Table, depth int) error {
+	name := db2go.QuoteName(a.dbType, t.Name()+a.suffix)
+	_, err := a.db.Exec(fmt.Sprintf("select 1 from %s where 1=0", name))
+	if err != nil {
+		format, ok := createLikeFormat[a.dbType]
+		if !ok {
+			return fmt.Errorf("archive table '%s' not found: %v", t.Name()+a.suffix, err)
+		}
+		_, err = a.db.Exec(fmt.Sprintf(format, name, db2go.QuoteName(a.dbType, t.Name())))
+		if err != nil {
+			return err
+		}
+	}
+	if a.child != ChildCascade || depth >= maxDepth {
+		return nil
+	}
+	for _, k := range a.children(t) {
+		if k.table == t {
+			continue
+		}
+		err = a.createTables(k.table, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 归档一批，返回选中的行数
+func (a *Archiver) runBatch(state *State) (int, error) {
+	tx, err := a.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return 0, err
+	}
+	query, args := a.selectSQL(state.LastKey)
+	rows, err := a.query(tx, a.table, query, args...)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	if len(rows) < 1 {
+		return 0, tx.Commit()
+	}
+	pk, _ := a.table.PrimaryKeyColumns()
+	last := a.key(a.table, pk, rows[len(rows)-1])
+	n := len(rows)
+	var skipped int
+	if a.child == ChildRestrict {
+		rows, err = a.unreferenced(tx, a.table, rows)
+		if err != nil {
+			_ = tx.Rollback()
+			return 0, err
+		}
+		skipped = n - len(rows)
+	}
+	err = a.move(tx, a.table, rows, 0)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	state.LastKey = last
+	state.Rows += int64(len(rows))
+	state.Skipped += int64(skipped)
+	return n, nil
+}
+
+// 满足条件并且大于last的一批，按主键排序
+func (a *Archiver) selectSQL(last []interface{}) (string, []interface{}) {
+	pk, _ := a.table.PrimaryKeyColumns()
+	var keys, params []string
+	for i, c := range pk {
+		keys = append(keys, db2go.QuoteName(a.dbType, c.Name()))
+		params = append(params, db2go.Placeholder(a.dbType, i+1))
+	}
+	var str strings.Builder
+	fmt.Fprintf(&str, "select %s from %s where (%s)", a.columns(a.table), db2go.QuoteName(a.dbType, a.table.Name()), a.where)
+	if len(last) > 0 {
+		if len(pk) > 1 {
+			fmt.Fprintf(&str, " and (%s)>(%s)", strings.Join(keys, ","), strings.Join(params, ","))
+		} else {
+			fmt.Fprintf(&str, " and %s>%s", keys[0], params[0])
+		}
+	}
+	fmt.Fprintf(&str, " order by %s limit %d%s", strings.Join(keys, ","), a.batch, lockSuffix[a.dbType])
+	return str.String(), last
+}
+
+func (a *Archiver) columns(t *db2go.Table) string {
+	var names []string
+	for _, c := range t.Columns() {
+		names = append(names, db2go.QuoteName(a.dbType, c.Name()))
+	}
+	return strings.Join(names, ",")
+}
+
+// 查询表的行，每行的值和t.Columns()一一对应
+func (a *Archiver) query(tx *sql.Tx, t *db2go.Table, query string, args ...interface{}) ([][]interface{}, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	n := len(t.Columns())
+	var result [][]interface{}
+	for rows.Next() {
+		values := make([]interface{}, n)
+		scans := make([]interface{}, n)
+		for i := range values {
+			scans[i] = &values[i]
+		}
+		err = rows.Scan(scans...)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			// 驱动会重用[]byte
+			if b, ok := v.([]byte); ok {
+				values[i] = append([]byte(nil), b...)
+			}
+		}
+		result = append(result, values)
+	}
+	return result, rows.Err()
+}
+
+// 行的columns的值，[]byte转换成string，可以保存到状态文件
+func (a *Archiver) key(t *db2go.Table, columns []*db2go.Column, row []interface{}) []interface{} {
+	var key []interface{}
+	for _, c := range columns {
+		v := row[a.index(t, c)]
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		key = append(key, v)
+	}
+	return key
+}
+
+func (a *Archiver) index(t *db2go.Table, c *db2go.Column) int {
+	for i, col := range t.Columns() {
+		if col == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// 用于比较的值
+func keyString(v interface{}) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// rows中column的不重复的值
+func (a *Archiver) values(t *db2go.Table, c *db2go.Column, rows [][]interface{}) []interface{} {
+	i := a.index(t, c)
+	has := make(map[string]bool)
+	var values []interface{}
+	for _, row := range rows {
+		if row[i] == nil || has[keyString(row[i])] {
+			continue
+		}
+		has[keyString(row[i])] = true
+		values = append(values, row[i])
+	}
+	return values
+}
+
+// column in (...)的条件
+func (a *Archiver) in(c *db2go.Column, n int) string {
+	var params []string
+	for i := 1; i <= n; i++ {
+		params = append(params, db2go.Placeholder(a.dbType, i))
+	}
+	return fmt.Sprintf("%s in (%s)", db2go.QuoteName(a.dbType, c.Name()), strings.Join(params, ","))
+}
+
+// 去掉被子表引用的行
+func (a *Archiver) unreferenced(tx *sql.Tx, t *db2go.Table, rows [][]interface{}) ([][]interface{}, error) {
+	for _, k := range a.children(t) {
+		values := a.values(t, k.ref, rows)
+		referenced := make(map[string]bool)
+		n := a.rowsPerStatement(1)
+		for len(values) > 0 {
+			part := values
+			if len(part) > n {
+				part = values[:n]
+			}
+			values = values[len(part):]
+			query := fmt.Sprintf("select distinct %s from %s where %s",
+				db2go.QuoteName(a.dbType, k.column.Name()), db2go.QuoteName(a.dbType, k.table.Name()), a.in(k.column, len(part)))
+			// 自引用时，同一批中互相引用的行也不归档
+			result, err := tx.Query(query, part...)
+			if err != nil {
+				return nil, err
+			}
+			for result.Next() {
+				var v interface{}
+				err = result.Scan(&v)
+				if err != nil {
+					_ = result.Close()
+					return nil, err
+				}
+				referenced[keyString(v)] = true
+			}
+			_ = result.Close()
+			if err = result.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if len(referenced) < 1 {
+			continue
+		}
+		i := a.index(t, k.ref)
+		var left [][]interface{}
+		for _, row := range rows {
+			if row[i] == nil || !referenced[keyString(row[i])] {
+				left = append(left, row)
+			}
+		}
+		rows = left
+	}
+	return rows, nil
+}
+
+// 先移动引用了rows的子表的行，再写归档表或者文件，然后删除
+func (a *Archiver) move(tx *sql.Tx, t *db2go.Table, rows [][]interface{}, depth int) error {
+	if len(rows) < 1 {
+		return nil
+	}
+	if a.child == ChildCascade {
+		if depth >= maxDepth {
+			return fmt.Errorf("table '%s': too many levels of children", t.Name())
+		}
+		for _, k := range a.children(t) {
+			values := a.values(t, k.ref, rows)
+			if len(values) < 1 {
+				continue
+			}
+			if pk, _ := k.table.PrimaryKeyColumns(); len(pk) < 1 {
+				return fmt.Errorf("child table '%s' has no primary key", k.table.Name())
+			}
+			var children [][]interface{}
+			n := a.rowsPerStatement(1)
+			for len(values) > 0 {
+				part := values
+				if len(part) > n {
+					part = values[:n]
+				}
+				values = values[len(part):]
+				query := fmt.Sprintf("select %s from %s where %s%s",
+					a.columns(k.table), db2go.QuoteName(a.dbType, k.table.Name()), a.in(k.column, len(part)), lockSuffix[a.dbType])
+				result, err := a.query(tx, k.table, query, part...)
+				if err != nil {
+					return err
+				}
+				children = append(children, result...)
+			}
+			// 自引用时，去掉本批中已有的行
+			if k.table == t {
+				children = a.exclude(t, children, rows)
+			}
+			err := a.move(tx, k.table, children, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if a.suffix != "" {
+		err := a.insert(tx, t, rows)
+		if err != nil {
+			return err
+		}
+	}
+	if a.writer != nil {
+		for _, row := range rows {
+			err := dump.WriteJSONRow(a.writer, t, row)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return a.delete(tx, t, rows)
+}
+
+// rows中去掉exclude中主键相同的行
+func (a *Archiver) exclude(t *db2go.Table, rows, exclude [][]interface{}) [][]interface{} {
+	pk, _ := t.PrimaryKeyColumns()
+	has := make(map[string]bool)
+	for _, row := range exclude {
+		has[fmt.Sprint(a.key(t, pk, row))] = true
+	}
+	var left [][]interface{}
+	for _, row := range rows {
+		if !has[fmt.Sprint(a.key(t, pk, row))] {
+			left = append(left, row)
+		}
+	}
+	return left
+}
+
+// 每行params个参数时，一条语句最多的行数
+func (a *Archiver) rowsPerStatement(params int) int {
+	max, ok := maxParams[a.dbType]
+	if !ok {
+		return math.MaxInt32
+	}
+	if n := max / params; n > 0 {
+		return n
+	}
+	return 1
+}
+
+// 插入归档表，参数太多时拆分成多条语句
+func (a *Archiver) insert(tx *sql.Tx, t *db2go.Table, rows [][]interface{}) error {
+	n := a.rowsPerStatement(len(t.Columns()))
+	for len(rows) > 0 {
+		part := rows
+		if len(part) > n {
+			part = rows[:n]
+		}
+		rows = rows[len(part):]
+		var str strings.Builder
+		var args []interface{}
+		fmt.Fprintf(&str, "insert into %s(%s) values", db2go.QuoteName(a.dbType, t.Name()+a.suffix), a.columns(t))
+		for i, row := range part {
+			if i > 0 {
+				str.WriteByte(',')
+			}
+			str.WriteByte('(')
+			for j, v := range row {
+				if j > 0 {
+					str.WriteByte(',')
+				}
+				args = append(args, v)
+				str.WriteString(db2go.Placeholder(a.dbType, len(args)))
+			}
+			str.WriteByte(')')
+		}
+		_, err := tx.Exec(str.String(), args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 按主键删除，参数太多时拆分成多条语句
+func (a *Archiver) delete(tx *sql.Tx, t *db2go.Table, rows [][]interface{}) error {
+	pk, _ := t.PrimaryKeyColumns()
+	var keys []string
+	for _, c := range pk {
+		keys = append(keys, db2go.QuoteName(a.dbType, c.Name()))
+	}
+	n := a.rowsPerStatement(len(pk))
+	for len(rows) > 0 {
+		part := rows
+		if len(part) > n {
+			part = rows[:n]
+		}
+		rows = rows[len(part):]
+		var str strings.Builder
+		var args []interface{}
+		fmt.Fprintf(&str, "delete from %s where (%s) in (", db2go.QuoteName(a.dbType, t.Name()), strings.Join(keys, ","))
+		for i, row := range part {
+			if i > 0 {
+				str.WriteByte(',')
+			}
+			str.WriteByte('(')
+			for j, v := range a.key(t, pk, row) {
+				if j > 0 {
+					str.WriteByte(',')
+				}
+				args = append(args, v)
+				str.WriteString(db2go.Placeholder(a.dbType, len(args)))
+			}
+			str.WriteByte(')')
+		}
+		str.WriteByte(')')
+		_, err := tx.Exec(str.String(), args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
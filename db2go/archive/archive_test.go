@@ -0,0 +1,175 @@
+package archive
+
+import (
+	"bytes"
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/qq51529210/db/db2go"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 读取testdata/schema.json的结构
+func testSchema(t *testing.T) *db2go.Schema {
+	s, err := db2go.ReadSchemaJSONFile("testdata/schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func testArchiver(t *testing.T, mode string) (*Archiver, *sql.DB) {
+	s := testSchema(t)
+	db, err := sql.Open(db2go.SQLITE, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	for _, s := range []string{
+		"create table orders(id integer primary key, created int)",
+		"create table orders_archive(id integer primary key, created int)",
+		"create table item(id integer primary key, order_id int references orders(id))",
+		"create table item_archive(id integer primary key, order_id int)",
+	} {
+		_, err = db.Exec(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 1; i <= 10; i++ {
+		_, err = db.Exec("insert into orders(id,created) values(?,?)", i, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 2和4被引用
+	_, err = db.Exec("insert into item(id,order_id) values(1,2),(2,4),(3,4),(4,9)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewArchiver(s, "orders", "created<=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.db = db
+	a.dbType = db2go.SQLITE
+	a.SetBatch(2)
+	a.SetArchiveSuffix(DefaultSuffix)
+	err = a.SetChildren(mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, db
+}
+
+func testCount(t *testing.T, db *sql.DB, query string) int {
+	var n int
+	err := db.QueryRow(query).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestRestrict(t *testing.T) {
+	a, db := testArchiver(t, ChildRestrict)
+	defer func() {
+		_ = db.Close()
+	}()
+	var buf bytes.Buffer
+	a.SetWriter(&buf)
+	a.SetStateFile(filepath.Join(t.TempDir(), "state.json"))
+	state, err := a.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Done || state.Rows != 3 || state.Skipped != 2 {
+		t.Fatal(state)
+	}
+	if n := testCount(t, db, "select count(*) from orders"); n != 7 {
+		t.Fatal(n)
+	}
+	if n := testCount(t, db, "select count(*) from orders_archive where id in (1,3,5)"); n != 3 {
+		t.Fatal(n)
+	}
+	if strings.Count(buf.String(), "\n") != 3 || !strings.Contains(buf.String(), `{"table":"orders","row":{"created":1,"id":1}}`) {
+		t.Fatal(buf.String())
+	}
+	// 已经完成
+	state, err = a.Run()
+	if err != nil || state.Rows != 3 {
+		t.Fatal(state, err)
+	}
+	// 条件不同
+	a.where = "created<=6"
+	_, err = a.Run()
+	if err == nil {
+		t.FailNow()
+	}
+}
+
+func TestCascade(t *testing.T) {
+	a, db := testArchiver(t, ChildCascade)
+	defer func() {
+		_ = db.Close()
+	}()
+	state, err := a.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Done || state.Rows != 5 || state.Skipped != 0 || len(state.LastKey) != 1 {
+		t.Fatal(state)
+	}
+	if n := testCount(t, db, "select count(*) from orders_archive"); n != 5 {
+		t.Fatal(n)
+	}
+	if n := testCount(t, db, "select count(*) from item_archive"); n != 3 {
+		t.Fatal(n)
+	}
+	if n := testCount(t, db, "select count(*) from item"); n != 1 {
+		t.Fatal(n)
+	}
+}
+
+func TestCascadeManyChildren(t *testing.T) {
+	a, db := testArchiver(t, ChildCascade)
+	defer func() {
+		_ = db.Close()
+	}()
+	// 插入归档表的参数超过sqlite的限制，需要拆分
+	_, err := db.Exec(`insert into item(id,order_id)
+with recursive n(i) as (select 10 union all select i+1 from n where i<20009) select i,1 from n`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := a.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Done || state.Rows != 5 {
+		t.Fatal(state)
+	}
+	if n := testCount(t, db, "select count(*) from item_archive"); n != 20003 {
+		t.Fatal(n)
+	}
+	if n := testCount(t, db, "select count(*) from item"); n != 1 {
+		t.Fatal(n)
+	}
+}
+
+func TestSelectSQL(t *testing.T) {
+	s := testSchema(t)
+	a, err := NewArchiver(s, "orders", "created<'2020-01-01'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, _ := a.selectSQL([]interface{}{"10"})
+	if q != "select `id`,`created` from `orders` where (created<'2020-01-01') and `id`>? order by `id` limit 1000 for update" {
+		t.Fatal(q)
+	}
+	_, err = NewArchiver(s, "orders", "")
+	if err == nil {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,139 @@
+/*
+审计表，给表t生成t_history表和INSERT/UPDATE/DELETE触发器，
+触发器把每次变化后的行（DELETE是删除前的行）连同操作，时间和数据库用户写到t_history。
+t增加列后再次生成，输出的是ALTER TABLE和重新创建的触发器，审计表没有变化时不输出。
+触发器是先删除再创建的，执行时两者之间对t的修改不会记录，应该在没有写入的时候执行。
+*/
+package audit
+
+import (
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"strings"
+)
+
+const (
+	DefaultSuffix   = "_history"
+	ColumnID        = "history_id"        // 自增主键
+	ColumnOperation = "history_operation" // INSERT，UPDATE或者DELETE
+	ColumnTime      = "history_time"      // 操作的时间
+	ColumnUser      = "history_user"      // 操作的数据库用户
+)
+
+var (
+	// 不同数据库的实现
+	dialects = map[string]dialect{
+		db2go.MYSQL: mysqlDialect{},
+	}
+	// 触发器的操作
+	operations = []string{"INSERT", "UPDATE", "DELETE"}
+)
+
+type dialect interface {
+	// 创建审计表
+	createTable(name string, t *db2go.Table) string
+	// 审计表增加列
+	addColumn(name string, c *db2go.Column) string
+	// 审计表修改列的类型
+	modifyColumn(name string, c *db2go.Column) string
+	// 删除触发器
+	dropTrigger(trigger string) string
+	// 创建触发器
+	createTrigger(trigger, name, operation string, t *db2go.Table) string
+}
+
+// 生成审计表和触发器的语句
+type Generator struct {
+	schema   *db2go.Schema
+	dialect  dialect
+	suffix   string
+	triggers bool // 审计表没有变化时也重新创建触发器
+}
+
+func NewGenerator(schema *db2go.Schema) (*Generator, error) {
+	d, ok := dialects[schema.DBType()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported db '%s'", schema.DBType())
+	}
+	return &Generator{schema: schema, dialect: d, suffix: DefaultSuffix}, nil
+}
+
+// 审计表名的后缀，默认是DefaultSuffix
+func (g *Generator) SetSuffix(suffix string) {
+	if suffix != "" {
+		g.suffix = suffix
+	}
+}
+
+// 审计表没有变化时是否也重新创建触发器，比如触发器被删除了
+func (g *Generator) SetRecreateTriggers(recreate bool) {
+	g.triggers = recreate
+}
+
+// 表的审计表名
+func (g *Generator) HistoryTable(table string) string {
+	return table + g.suffix
+}
+
+// 触发器的名称，比如user_history_insert
+func (g *Generator) Trigger(table, operation string) string {
+	return g.HistoryTable(table) + "_" + strings.ToLower(operation)
+}
+
+// 生成表的语句，审计表不存在时创建，存在时补上新增的列和修改了类型的列，然后重新创建触发器
+func (g *Generator) Generate(table string) ([]string, error) {
+	t := g.schema.GetTable(table)
+	if t == nil {
+		return nil, fmt.Errorf("table '%s' not found", table)
+	}
+	if strings.HasSuffix(t.Name(), g.suffix) {
+		return nil, fmt.Errorf("table '%s' is a history table", table)
+	}
+	for _, c := range t.Columns() {
+		switch c.Name() {
+		case ColumnID, ColumnOperation, ColumnTime, ColumnUser:
+			return nil, fmt.Errorf("table '%s': column '%s' is reserved", table, c.Name())
+		}
+	}
+	name := g.HistoryTable(t.Name())
+	var stmts []string
+	h := g.schema.GetTable(name)
+	if h == nil {
+		stmts = append(stmts, g.dialect.createTable(name, t))
+	} else {
+		for _, c := range t.Columns() {
+			hc := h.GetColumn(c.Name())
+			if hc == nil {
+				stmts = append(stmts, g.dialect.addColumn(name, c))
+				continue
+			}
+			if !strings.EqualFold(hc.Type(), c.Type()) {
+				stmts = append(stmts, g.dialect.modifyColumn(name, c))
+			}
+		}
+		if len(stmts) < 1 && !g.triggers && !g.hasRemovedColumn(h, t) {
+			return nil, nil
+		}
+	}
+	// 触发器列出了所有的列，要重新创建
+	for _, op := range operations {
+		trigger := g.Trigger(t.Name(), op)
+		stmts = append(stmts, g.dialect.dropTrigger(trigger))
+		stmts = append(stmts, g.dialect.createTrigger(trigger, name, op, t))
+	}
+	return stmts, nil
+}
+
+// 审计表是否有t已经删除的列，无法知道触发器是否已经去掉了这个列，所以要重新创建
+func (g *Generator) hasRemovedColumn(h, t *db2go.Table) bool {
+	for _, c := range h.Columns() {
+		switch c.Name() {
+		case ColumnID, ColumnOperation, ColumnTime, ColumnUser:
+			continue
+		}
+		if t.GetColumn(c.Name()) == nil {
+			return true
+		}
+	}
+	return false
+}
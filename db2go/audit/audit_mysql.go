@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"strings"
+)
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) quote(name string) string {
+	return db2go.QuoteName(db2go.MYSQL, name)
+}
+
+// 审计表的列都可以为NULL，不要自增，唯一和默认值
+func (d mysqlDialect) column(c *db2go.Column) string {
+	return d.quote(c.Name()) + " " + c.Type() + " NULL"
+}
+
+func (d mysqlDialect) createTable(name string, t *db2go.Table) string {
+	lines := []string{
+		"  " + d.quote(ColumnID) + " bigint unsigned NOT NULL AUTO_INCREMENT",
+		"  " + d.quote(ColumnOperation) + " enum('INSERT','UPDATE','DELETE') NOT NULL",
+		"  " + d.quote(ColumnTime) + " datetime(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)",
+		"  " + d.quote(ColumnUser) + " varchar(288) NOT NULL",
+	}
+	for _, c := range t.Columns() {
+		lines = append(lines, "  "+d.column(c))
+	}
+	lines = append(lines, "  PRIMARY KEY ("+d.quote(ColumnID)+")")
+	// 按主键查询一行的历史
+	pk, _ := t.PrimaryKeyColumns()
+	if len(pk) > 0 {
+		var names []string
+		for _, c := range pk {
+			names = append(names, d.quote(c.Name()))
+		}
+		lines = append(lines, "  KEY "+d.quote(name+"_pk_index")+" ("+strings.Join(names, ",")+")")
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n)", d.quote(name), strings.Join(lines, ",\n"))
+}
+
+func (d mysqlDialect) addColumn(name string, c *db2go.Column) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", d.quote(name), d.column(c))
+}
+
+func (d mysqlDialect) modifyColumn(name string, c *db2go.Column) string {
+	return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s", d.quote(name), d.column(c))
+}
+
+func (d mysqlDialect) dropTrigger(trigger string) string {
+	return "DROP TRIGGER IF EXISTS " + d.quote(trigger)
+}
+
+// 只有一条语句，不需要BEGIN END，也就不需要DELIMITER
+func (d mysqlDialect) createTrigger(trigger, name, operation string, t *db2go.Table) string {
+	row := "NEW"
+	if operation == "DELETE" {
+		row = "OLD"
+	}
+	columns := []string{d.quote(ColumnOperation), d.quote(ColumnUser)}
+	values := []string{"'" + operation + "'", "USER()"}
+	for _, c := range t.Columns() {
+		columns = append(columns, d.quote(c.Name()))
+		values = append(values, row+"."+d.quote(c.Name()))
+	}
+	return fmt.Sprintf("CREATE TRIGGER %s AFTER %s ON %s FOR EACH ROW INSERT INTO %s (%s) VALUES (%s)",
+		d.quote(trigger), operation, d.quote(t.Name()), d.quote(name), strings.Join(columns, ","), strings.Join(values, ","))
+}
@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"github.com/qq51529210/db/db2go"
+	"strings"
+	"testing"
+)
+
+// 读取testdata/schema.json的结构
+func testSchema(t *testing.T) *db2go.Schema {
+	s, err := db2go.ReadSchemaJSONFile("testdata/schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestGenerate(t *testing.T) {
+	s := testSchema(t)
+	g, err := NewGenerator(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 创建
+	stmts, err := g.Generate("role")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 7 || !strings.HasPrefix(stmts[0], "CREATE TABLE `role_history`") ||
+		!strings.Contains(stmts[0], "`name` varchar(32) NULL") ||
+		!strings.Contains(stmts[0], "KEY `role_history_pk_index` (`id`)") {
+		t.Fatal(stmts)
+	}
+	if stmts[6] != "CREATE TRIGGER `role_history_delete` AFTER DELETE ON `role` FOR EACH ROW INSERT INTO `role_history` "+
+		"(`history_operation`,`history_user`,`id`,`name`) VALUES ('DELETE',USER(),OLD.`id`,OLD.`name`)" {
+		t.Fatal(stmts[6])
+	}
+	// 同步
+	stmts, err = g.Generate("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 8 ||
+		stmts[0] != "ALTER TABLE `user_history` MODIFY COLUMN `name` varchar(32) NULL" ||
+		stmts[1] != "ALTER TABLE `user_history` ADD COLUMN `email` varchar(64) NULL" ||
+		stmts[2] != "DROP TRIGGER IF EXISTS `user_history_insert`" ||
+		!strings.Contains(stmts[3], "NEW.`email`") {
+		t.Fatal(stmts)
+	}
+	// 没有变化
+	stmts, err = g.Generate("dept")
+	if err != nil || len(stmts) != 0 {
+		t.Fatal(stmts, err)
+	}
+	g.SetRecreateTriggers(true)
+	stmts, err = g.Generate("dept")
+	if err != nil || len(stmts) != 6 {
+		t.Fatal(stmts, err)
+	}
+	g.SetRecreateTriggers(false)
+	// 删除了列，触发器要去掉这个列
+	stmts, err = g.Generate("post")
+	if err != nil || len(stmts) != 6 || strings.Contains(stmts[1], "title") {
+		t.Fatal(stmts, err)
+	}
+	// 审计表
+	_, err = g.Generate("user_history")
+	if err == nil {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,428 @@
+/*
+跨数据库复制表，按目标数据库转换列的类型创建表，按主键的顺序分批复制，
+中断后再次执行，从目标表最大的主键继续。字符串的顺序和两个数据库的排序规则有关，
+主键有非数字的列时不能继续。
+*/
+package datacopy
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"github.com/qq51529210/db/db2go/mask"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultBatch = 1000 // 默认每批的行数
+)
+
+var (
+	errNoDB = errors.New("schema has no database connection")
+	// 一条语句最多的参数
+	maxParams = map[string]int{
+		db2go.MYSQL:    65535,
+		db2go.POSTGRES: 65535,
+		db2go.SQLITE:   999,
+	}
+	// 复制后重置自增的序列，参数是表名字符串，列名字符串，列名，表名
+	resetSequenceFormat = map[string]string{
+		db2go.POSTGRES: "select setval(pg_get_serial_sequence(%s, %s), coalesce(max(%s), 0) + 1, false) from %s",
+	}
+)
+
+// 复制的进度
+type Progress struct {
+	Table string
+	Rows  int64 // 目标表已有的行数，包括之前复制的
+	Total int64 // 源表的估算行数
+	Done  bool  // 表已经复制完
+}
+
+func (p *Progress) String() string {
+	if p.Done {
+		return fmt.Sprintf("%s: %d rows, done", p.Table, p.Rows)
+	}
+	return fmt.Sprintf("%s: %d/~%d rows", p.Table, p.Rows, p.Total)
+}
+
+// 复制器
+type Copier struct {
+	from     *db2go.Schema
+	to       *sql.DB
+	toType   string
+	batch    int
+	tables   map[string]bool // 复制的表，空表示全部
+	create   bool            // 目标表不存在时创建
+	masker   *mask.Masker
+	progress func(*Progress)
+}
+
+// 从from的连接池复制到to，toType是目标数据库的类型
+func NewCopier(from *db2go.Schema, toType string, to *sql.DB) *Copier {
+	c := new(Copier)
+	c.from = from
+	c.to = to
+	c.toType = toType
+	c.batch = DefaultBatch
+	c.tables = make(map[string]bool)
+	c.create = true
+	c.progress = func(*Progress) {}
+	return c
+}
+
+// 每批的行数，每批一个事务
+func (c *Copier) SetBatch(rows int) {
+	if rows < 1 {
+		rows = 1
+	}
+	c.batch = rows
+}
+
+// 只复制这些表，被引用的表不会自动加入
+func (c *Copier) SetTables(tables ...string) {
+	for _, t := range tables {
+		c.tables[t] = true
+	}
+}
+
+// 目标表不存在时是否创建，默认创建
+func (c *Copier) SetCreateTables(create bool) {
+	c.create = create
+}
+
+// 复制时对数据脱敏
+func (c *Copier) SetMasker(masker *mask.Masker) {
+	c.masker = masker
+}
+
+// 每批复制完后回调
+func (c *Copier) SetProgress(fn func(*Progress)) {
+	c.progress = fn
+}
+
+// 按外键依赖的顺序复制
+func (c *Copier) Copy() error {
+	if c.from.DB() == nil {
+		return errNoDB
+	}
+	for name := range c.tables {
+		if c.from.GetTable(name) == nil {
+			return fmt.Errorf("table '%s' not found", name)
+		}
+	}
+	var tables []*db2go.Table
+	for _, t := range c.from.SortedTables() {
+		if len(c.tables) > 0 && !c.tables[t.Name()] {
+			continue
+		}
+		// 复制之前检查所有的表
+		err := c.checkMask(t)
+		if err != nil {
+			return fmt.Errorf("table '%s': %v", t.Name(), err)
+		}
+		tables = append(tables, t)
+	}
+	for _, t := range tables {
+		err := c.CopyTable(t)
+		if err != nil {
+			return fmt.Errorf("table '%s': %v", t.Name(), err)
+		}
+	}
+	return nil
+}
+
+// 复制一个表
+func (c *Copier) CopyTable(t *db2go.Table) error {
+	if c.from.DB() == nil {
+		return errNoDB
+	}
+	err := c.checkMask(t)
+	if err != nil {
+		return err
+	}
+	err = c.createTable(t)
+	if err != nil {
+		return err
+	}
+	pk, _ := t.PrimaryKeyColumns()
+	p := &Progress{Table: t.Name(), Total: t.Stats().Rows()}
+	p.Rows, err = c.count(t)
+	if err != nil {
+		return err
+	}
+	var last []interface{}
+	if p.Rows > 0 {
+		err = checkResume(pk)
+		if err != nil {
+			return fmt.Errorf("target table has %d rows, %s", p.Rows, err.Error())
+		}
+		last, err = c.lastKey(t, pk)
+		if err != nil {
+			return err
+		}
+	}
+	rows, err := c.from.DB().Query(c.selectSQL(t, pk, last), last...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	columns := t.Columns()
+	var batch [][]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		scans := make([]interface{}, len(columns))
+		for i := range values {
+			scans[i] = &values[i]
+		}
+		err = rows.Scan(scans...)
+		if err != nil {
+			return err
+		}
+		for i, col := range columns {
+			values[i] = c.value(t, col, values[i])
+		}
+		batch = append(batch, values)
+		if len(batch) >= c.batch {
+			err = c.insert(t, batch)
+			if err != nil {
+				return err
+			}
+			p.Rows += int64(len(batch))
+			c.progress(p)
+			batch = batch[:0]
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		err = c.insert(t, batch)
+		if err != nil {
+			return err
+		}
+		p.Rows += int64(len(batch))
+	}
+	err = c.resetSequence(t)
+	if err != nil {
+		return err
+	}
+	p.Done = true
+	c.progress(p)
+	return nil
+}
+
+// 转换成目标数据库可以使用的值，然后脱敏
+func (c *Copier) value(t *db2go.Table, col *db2go.Column, v interface{}) interface{} {
+	number := false
+	switch col.GoType() {
+	case "int8", "int16", "int32", "int", "int64", "uint8", "uint16", "uint32", "uint", "uint64", "float32", "float64",
+		"sql.NullInt32", "sql.NullInt64", "sql.NullFloat64":
+		number = true
+	}
+	switch x := v.(type) {
+	case []byte:
+		if col.GoType() == "[]byte" {
+			b := make([]byte, len(x))
+			copy(b, x)
+			v = b
+		} else if number {
+			v = json.Number(x)
+		} else {
+			v = string(x)
+		}
+	case int64:
+		v = json.Number(strconv.FormatInt(x, 10))
+	case float64:
+		v = json.Number(strconv.FormatFloat(x, 'g', -1, 64))
+	}
+	if c.masker != nil {
+		v = c.masker.Mask(t, col, v)
+	}
+	// 驱动不认识json.Number
+	if n, ok := v.(json.Number); ok {
+		v = string(n)
+	}
+	return v
+}
+
+// 继续复制使用目标表最大的主键，主键脱敏后和源表的不同，所以不允许
+func (c *Copier) checkMask(t *db2go.Table) error {
+	if c.masker == nil {
+		return nil
+	}
+	pk, _ := t.PrimaryKeyColumns()
+	for _, col := range pk {
+		if c.masker.Rule(t, col) != nil {
+			return fmt.Errorf("primary key column '%s' can not be masked", col.Name())
+		}
+	}
+	return nil
+}
+
+// 目标表不存在时创建
+func (c *Copier) createTable(t *db2go.Table) error {
+	_, err := c.to.Exec(fmt.Sprintf("select 1 from %s where 1=0", db2go.QuoteName(c.toType, t.Name())))
+	if err == nil {
+		return nil
+	}
+	if !c.create {
+		return fmt.Errorf("target table not found: %v", err)
+	}
+	ddl, err := db2go.TableDDL(c.toType, t)
+	if err != nil {
+		return err
+	}
+	for _, s := range db2go.SplitStatements(ddl) {
+		_, err = c.to.Exec(s)
+		if err != nil {
+			return fmt.Errorf("%s: %v", s, err)
+		}
+	}
+	return nil
+}
+
+func (c *Copier) count(t *db2go.Table) (int64, error) {
+	var n int64
+	err := c.to.QueryRow(fmt.Sprintf("select count(*) from %s", db2go.QuoteName(c.toType, t.Name()))).Scan(&n)
+	return n, err
+}
+
+// 数字的类型，在所有数据库中的顺序相同
+var numericTypes = map[string]bool{
+	"tinyint": true, "smallint": true, "mediumint": true, "int": true, "integer": true, "bigint": true,
+	"smallserial": true, "serial": true, "bigserial": true, "decimal": true, "numeric": true,
+	"float": true, "double": true, "real": true,
+}
+
+// 是否可以从目标表最大的主键继续
+func checkResume(pk []*db2go.Column) error {
+	if len(pk) < 1 {
+		return errors.New("no primary key to resume from")
+	}
+	for _, col := range pk {
+		if !numericTypes[col.DataType()] {
+			return fmt.Errorf("can not resume from non-numeric primary key column '%s'", col.Name())
+		}
+	}
+	return nil
+}
+
+// 目标表最大的主键
+func (c *Copier) lastKey(t *db2go.Table, pk []*db2go.Column) ([]interface{}, error) {
+	var names []string
+	for _, col := range pk {
+		names = append(names, db2go.QuoteName(c.toType, col.Name()))
+	}
+	key := make([]interface{}, len(pk))
+	scans := make([]interface{}, len(pk))
+	for i := range key {
+		scans[i] = &key[i]
+	}
+	err := c.to.QueryRow(fmt.Sprintf("select %s from %s order by %s limit 1",
+		strings.Join(names, ","), db2go.QuoteName(c.toType, t.Name()), strings.Join(names, " desc,")+" desc")).Scan(scans...)
+	return key, err
+}
+
+// 源表大于last的行，按主键排序
+func (c *Copier) selectSQL(t *db2go.Table, pk []*db2go.Column, last []interface{}) string {
+	dbType := c.from.DBType()
+	var names []string
+	for _, col := range t.Columns() {
+		names = append(names, db2go.QuoteName(dbType, col.Name()))
+	}
+	var str strings.Builder
+	fmt.Fprintf(&str, "select %s from %s", strings.Join(names, ","), db2go.QuoteName(dbType, t.Name()))
+	if len(pk) < 1 {
+		return str.String()
+	}
+	var keys, params []string
+	for i, col := range pk {
+		keys = append(keys, db2go.QuoteName(dbType, col.Name()))
+		params = append(params, db2go.Placeholder(dbType, i+1))
+	}
+	if len(last) > 0 {
+		if len(pk) > 1 {
+			fmt.Fprintf(&str, " where (%s)>(%s)", strings.Join(keys, ","), strings.Join(params, ","))
+		} else {
+			fmt.Fprintf(&str, " where %s>%s", keys[0], params[0])
+		}
+	}
+	str.WriteString(" order by ")
+	str.WriteString(strings.Join(keys, ","))
+	return str.String()
+}
+
+// 在一个事务中插入，参数太多时拆分成多条语句
+func (c *Copier) insert(t *db2go.Table, rows [][]interface{}) error {
+	columns := t.Columns()
+	n := len(rows)
+	if max, ok := maxParams[c.toType]; ok && n*len(columns) > max {
+		n = max / len(columns)
+	}
+	var names []string
+	for _, col := range columns {
+		names = append(names, db2go.QuoteName(c.toType, col.Name()))
+	}
+	tx, err := c.to.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	for len(rows) > 0 {
+		part := rows
+		if len(part) > n {
+			part = rows[:n]
+		}
+		rows = rows[len(part):]
+		var str strings.Builder
+		var args []interface{}
+		fmt.Fprintf(&str, "insert into %s(%s) values", db2go.QuoteName(c.toType, t.Name()), strings.Join(names, ","))
+		for i, row := range part {
+			if i > 0 {
+				str.WriteByte(',')
+			}
+			str.WriteByte('(')
+			for j, v := range row {
+				if j > 0 {
+					str.WriteByte(',')
+				}
+				args = append(args, v)
+				str.WriteString(db2go.Placeholder(c.toType, len(args)))
+			}
+			str.WriteByte(')')
+		}
+		_, err = tx.Exec(str.String(), args...)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// 插入了自增列的值，需要重置序列
+func (c *Copier) resetSequence(t *db2go.Table) error {
+	format, ok := resetSequenceFormat[c.toType]
+	if !ok {
+		return nil
+	}
+	for _, col := range t.Columns() {
+		if !col.IsAutoIncrement() {
+			continue
+		}
+		table := db2go.QuoteName(c.toType, t.Name())
+		column := db2go.QuoteName(c.toType, col.Name())
+		_, err := c.to.Exec(fmt.Sprintf(format, db2go.QuoteString(c.toType, table), db2go.QuoteString(c.toType, col.Name()), column, table))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
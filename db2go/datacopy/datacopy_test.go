@@ -0,0 +1,91 @@
+package datacopy
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/qq51529210/db/db2go"
+	"github.com/qq51529210/db/db2go/mask"
+	"strconv"
+	"testing"
+)
+
+// 读取testdata/schema.json的结构
+func testSchema(t *testing.T) *db2go.Schema {
+	s, err := db2go.ReadSchemaJSONFile("testdata/schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCopySQLite(t *testing.T) {
+	s := testSchema(t)
+	to, err := sql.Open(db2go.SQLITE, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = to.Close()
+	}()
+	to.SetMaxOpenConns(1)
+	c := NewCopier(s, db2go.SQLITE, to)
+	table := s.GetTable("t1")
+	err = c.createTable(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 参数超过999，拆分成多条语句
+	var rows [][]interface{}
+	for i := 1; i <= 500; i++ {
+		rows = append(rows, []interface{}{
+			c.value(table, table.GetColumn("id"), []byte(strconv.Itoa(i))),
+			c.value(table, table.GetColumn("name"), []byte("n")),
+			c.value(table, table.GetColumn("data"), []byte{0, 1}),
+		})
+	}
+	err = c.insert(table, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := c.count(table)
+	if err != nil || n != 500 {
+		t.Fatal(n, err)
+	}
+	pk, _ := table.PrimaryKeyColumns()
+	last, err := c.lastKey(table, pk)
+	if err != nil || last[0] != int64(500) {
+		t.Fatal(last, err)
+	}
+	if q := c.selectSQL(table, pk, last); q != "select `id`,`name`,`data` from `t1` where `id`>? order by `id`" {
+		t.Fatal(q)
+	}
+	// 字符串的主键不能继续
+	if err = checkResume(pk); err != nil {
+		t.Fatal(err)
+	}
+	err = checkResume([]*db2go.Column{pk[0], table.GetColumn("name")})
+	if err == nil || err.Error() != "can not resume from non-numeric primary key column 'name'" {
+		t.Fatal(err)
+	}
+	// 主键脱敏后无法继续复制
+	for _, str := range []string{"t1.name=hash", "t1.id=format"} {
+		r, err := mask.ParseRule(str)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := mask.NewMasker(s, "salt", r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SetMasker(m)
+		err = c.checkMask(table)
+		if (err != nil) != (str == "t1.id=format") {
+			t.Fatal(str, err)
+		}
+	}
+	var data []byte
+	err = to.QueryRow(`select data from t1 where id=1`).Scan(&data)
+	if err != nil || string(data) != "\x00\x01" {
+		t.Fatal(data, err)
+	}
+}
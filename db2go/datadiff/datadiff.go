@@ -0,0 +1,367 @@
+/*
+比较两个数据库的表的数据，按主键的顺序分块读取，比较每一行的hash，
+返回from变成to需要插入，删除和修改的行，以及修改的列。
+*/
+package datadiff
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"strings"
+	"time"
+)
+
+const (
+	RowAdded   = "row-added"   // to有，from没有
+	RowRemoved = "row-removed" // from有，to没有
+	RowChanged = "row-changed" // 都有，但是列的值不同
+
+	DefaultChunk = 1000 // 默认每次读取的行数
+)
+
+var (
+	errNoDB = errors.New("schema has no database connection")
+)
+
+// 一个列的不同
+type ColumnDiff struct {
+	Column string      `json:"column"`
+	From   interface{} `json:"from"`
+	To     interface{} `json:"to"`
+}
+
+// 一行的不同，值是nil或者string
+type RowDiff struct {
+	Type    string                 `json:"type"`
+	Table   string                 `json:"table"`
+	Key     map[string]interface{} `json:"key"`               // 主键
+	Row     map[string]interface{} `json:"row,omitempty"`     // 插入的行或者删除的行
+	Columns []*ColumnDiff          `json:"columns,omitempty"` // 修改的列
+	keys    []string               // 主键的顺序
+}
+
+func (d *RowDiff) String() string {
+	var str strings.Builder
+	str.WriteString(d.Type)
+	str.WriteByte(' ')
+	str.WriteString(d.Table)
+	str.WriteByte('(')
+	for i, k := range d.keys {
+		if i > 0 {
+			str.WriteByte(',')
+		}
+		fmt.Fprintf(&str, "%s=%s", k, text(d.Key[k]))
+	}
+	str.WriteByte(')')
+	for i, c := range d.Columns {
+		if i == 0 {
+			str.WriteString(": ")
+		} else {
+			str.WriteString(", ")
+		}
+		fmt.Fprintf(&str, "%s %s -> %s", c.Column, text(c.From), text(c.To))
+	}
+	return str.String()
+}
+
+func text(v interface{}) string {
+	if v == nil {
+		return "NULL"
+	}
+	return fmt.Sprintf("%q", v)
+}
+
+// 一行数据
+type row struct {
+	values []interface{}
+	key    []interface{}
+	hash   [sha256.Size]byte
+}
+
+func (r *row) keyString() string {
+	var str strings.Builder
+	for _, v := range r.key {
+		if v == nil {
+			str.WriteString("\x01")
+		} else {
+			str.WriteString(v.(string))
+		}
+		str.WriteByte(0)
+	}
+	return str.String()
+}
+
+// 数据比较
+type Differ struct {
+	from     *db2go.Schema
+	to       *db2go.Schema
+	fromDB   *sql.DB
+	toDB     *sql.DB
+	fromType string
+	toType   string
+	chunk    int
+}
+
+// 使用两个结构的连接池比较
+func NewDiffer(from, to *db2go.Schema) *Differ {
+	d := new(Differ)
+	d.from = from
+	d.to = to
+	d.fromDB = from.DB()
+	d.toDB = to.DB()
+	d.fromType = from.DBType()
+	d.toType = to.DBType()
+	d.chunk = DefaultChunk
+	return d
+}
+
+// 每次读取的行数
+func (d *Differ) SetChunk(rows int) {
+	if rows < 1 {
+		rows = 1
+	}
+	d.chunk = rows
+}
+
+// 比较两边都有的，有主键的表
+func (d *Differ) Diff(fn func(*RowDiff) error) error {
+	for _, t := range d.from.SortedTables() {
+		if d.to.GetTable(t.Name()) == nil {
+			continue
+		}
+		if pk, _ := t.PrimaryKeyColumns(); len(pk) < 1 {
+			continue
+		}
+		err := d.DiffTable(t.Name(), fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 比较一个表，fn返回错误则停止
+func (d *Differ) DiffTable(name string, fn func(*RowDiff) error) error {
+	if d.fromDB == nil || d.toDB == nil {
+		return errNoDB
+	}
+	ft, tt := d.from.GetTable(name), d.to.GetTable(name)
+	if ft == nil || tt == nil {
+		return fmt.Errorf("table '%s' not found", name)
+	}
+	pk, _ := ft.PrimaryKeyColumns()
+	if len(pk) < 1 {
+		return fmt.Errorf("table '%s' has no primary key", name)
+	}
+	// 两边都有的列，主键在前面
+	var columns []string
+	for _, c := range pk {
+		if tt.GetColumn(c.Name()) == nil {
+			return fmt.Errorf("table '%s': primary key column '%s' not found", name, c.Name())
+		}
+		columns = append(columns, c.Name())
+	}
+	for _, c := range ft.Columns() {
+		if !c.IsPrimaryKey() && tt.GetColumn(c.Name()) != nil {
+			columns = append(columns, c.Name())
+		}
+	}
+	w := &walker{name: name, columns: columns, keys: len(pk), fn: fn}
+	var last []interface{}
+	for {
+		a, err := w.query(d.fromDB, d.fromType, last, nil, d.chunk)
+		if err != nil {
+			return err
+		}
+		// from没有了，to剩下的都是插入的
+		if len(a) < 1 {
+			for {
+				b, err := w.query(d.toDB, d.toType, last, nil, d.chunk)
+				if err != nil {
+					return err
+				}
+				if len(b) < 1 {
+					return nil
+				}
+				err = w.merge(nil, b)
+				if err != nil {
+					return err
+				}
+				last = b[len(b)-1].key
+			}
+		}
+		// to在这个主键范围的行
+		upper := a[len(a)-1].key
+		b, err := w.query(d.toDB, d.toType, last, upper, 0)
+		if err != nil {
+			return err
+		}
+		err = w.merge(a, b)
+		if err != nil {
+			return err
+		}
+		last = upper
+	}
+}
+
+// 一个表的比较
+type walker struct {
+	name    string
+	columns []string // 前keys个是主键
+	keys    int
+	fn      func(*RowDiff) error
+}
+
+// 按主键的顺序读取，主键大于after并且小于等于upper，limit为0表示不限制
+func (w *walker) query(db *sql.DB, dbType string, after, upper []interface{}, limit int) ([]*row, error) {
+	var names []string
+	for _, c := range w.columns {
+		names = append(names, db2go.QuoteName(dbType, c))
+	}
+	key := strings.Join(names[:w.keys], ",")
+	if w.keys > 1 {
+		key = "(" + key + ")"
+	}
+	var args []interface{}
+	tuple := func(values []interface{}) string {
+		var ps []string
+		for _, v := range values {
+			args = append(args, v)
+			ps = append(ps, db2go.Placeholder(dbType, len(args)))
+		}
+		if len(ps) > 1 {
+			return "(" + strings.Join(ps, ",") + ")"
+		}
+		return ps[0]
+	}
+	var where []string
+	if after != nil {
+		where = append(where, key+">"+tuple(after))
+	}
+	if upper != nil {
+		where = append(where, key+"<="+tuple(upper))
+	}
+	var str strings.Builder
+	fmt.Fprintf(&str, "select %s from %s", strings.Join(names, ","), db2go.QuoteName(dbType, w.name))
+	if len(where) > 0 {
+		str.WriteString(" where ")
+		str.WriteString(strings.Join(where, " and "))
+	}
+	str.WriteString(" order by ")
+	str.WriteString(strings.Join(names[:w.keys], ","))
+	if limit > 0 {
+		fmt.Fprintf(&str, " limit %d", limit)
+	}
+	rows, err := db.Query(str.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("table '%s': %v", w.name, err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	var result []*row
+	for rows.Next() {
+		values := make([]interface{}, len(w.columns))
+		scans := make([]interface{}, len(values))
+		for i := range values {
+			scans[i] = &values[i]
+		}
+		err = rows.Scan(scans...)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, newRow(values, w.keys))
+	}
+	return result, rows.Err()
+}
+
+// 值转换成nil或者string，并计算hash
+func newRow(values []interface{}, keys int) *row {
+	r := new(row)
+	h := sha256.New()
+	for i, v := range values {
+		switch v := v.(type) {
+		case nil:
+		case []byte:
+			values[i] = string(v)
+		case string:
+		case time.Time:
+			values[i] = v.Format("2006-01-02 15:04:05.999999")
+		default:
+			values[i] = fmt.Sprint(v)
+		}
+		if values[i] == nil {
+			_, _ = h.Write([]byte{0})
+		} else {
+			_, _ = h.Write([]byte{1})
+			_, _ = h.Write([]byte(values[i].(string)))
+			_, _ = h.Write([]byte{0})
+		}
+	}
+	r.values = values
+	r.key = values[:keys]
+	h.Sum(r.hash[:0])
+	return r
+}
+
+// 比较主键范围相同的两组行，先返回删除和修改的行，再返回插入的行
+func (w *walker) merge(a, b []*row) error {
+	to := make(map[string]*row)
+	for _, r := range b {
+		to[r.keyString()] = r
+	}
+	for _, r := range a {
+		k := r.keyString()
+		tr, ok := to[k]
+		if !ok {
+			err := w.fn(w.diff(RowRemoved, r, nil))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		delete(to, k)
+		if r.hash != tr.hash {
+			err := w.fn(w.diff(RowChanged, r, tr))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, r := range b {
+		if _, ok := to[r.keyString()]; ok {
+			err := w.fn(w.diff(RowAdded, nil, r))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *walker) diff(typ string, from, to *row) *RowDiff {
+	d := &RowDiff{Type: typ, Table: w.name, Key: make(map[string]interface{}), keys: w.columns[:w.keys]}
+	r := from
+	if r == nil {
+		r = to
+	}
+	for i, k := range r.key {
+		d.Key[w.columns[i]] = k
+	}
+	if typ != RowChanged {
+		d.Row = make(map[string]interface{})
+		for i, v := range r.values {
+			d.Row[w.columns[i]] = v
+		}
+		return d
+	}
+	for i := w.keys; i < len(w.columns); i++ {
+		if from.values[i] != to.values[i] {
+			d.Columns = append(d.Columns, &ColumnDiff{Column: w.columns[i], From: from.values[i], To: to.values[i]})
+		}
+	}
+	return d
+}
@@ -0,0 +1,118 @@
+package datadiff
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/qq51529210/db/db2go"
+	"strings"
+	"testing"
+)
+
+// 读取testdata/schema.json的结构
+func testSchema(t *testing.T) *db2go.Schema {
+	s, err := db2go.ReadSchemaJSONFile("testdata/schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func rows(keys int, values ...[]interface{}) []*row {
+	var rs []*row
+	for _, v := range values {
+		rs = append(rs, newRow(v, keys))
+	}
+	return rs
+}
+
+func TestMerge(t *testing.T) {
+	var diffs []*RowDiff
+	w := &walker{name: "t1", columns: []string{"id", "name", "data"}, keys: 1, fn: func(d *RowDiff) error {
+		diffs = append(diffs, d)
+		return nil
+	}}
+	a := rows(1, []interface{}{[]byte("1"), []byte("a"), nil}, []interface{}{int64(2), "b", nil}, []interface{}{"3", "c", nil})
+	b := rows(1, []interface{}{"1", "a", nil}, []interface{}{"2", nil, []byte{1}}, []interface{}{"4", "d", nil})
+	err := w.merge(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result []string
+	for _, d := range diffs {
+		result = append(result, d.String())
+	}
+	if strings.Join(result, "\n") != `row-changed t1(id="2"): name "b" -> NULL, data NULL -> "\x01"
+row-removed t1(id="3")
+row-added t1(id="4")` {
+		t.Fatal(strings.Join(result, "\n"))
+	}
+	s := testSchema(t)
+	result = result[:0]
+	for _, d := range diffs {
+		q, err := ReconcileSQL(s, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, q)
+	}
+	if strings.Join(result, "\n") != "update `t1` set `name`=NULL,`data`=X'01' where `id`=2;\n"+
+		"delete from `t1` where `id`=3;\n"+
+		"insert into `t1`(`id`,`name`,`data`) values(4,'d',NULL);" {
+		t.Fatal(strings.Join(result, "\n"))
+	}
+}
+
+func testDB(t *testing.T, rows ...string) *sql.DB {
+	db, err := sql.Open(db2go.SQLITE, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec("create table t1(id integer primary key, name varchar(8), data blob)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range rows {
+		_, err = db.Exec("insert into t1(id,name) values" + r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestDiffTable(t *testing.T) {
+	s := testSchema(t)
+	from := testDB(t, "(1,'a')", "(2,'b')", "(3,'c')", "(5,'e')", "(6,'f')")
+	defer func() {
+		_ = from.Close()
+	}()
+	to := testDB(t, "(1,'a')", "(2,'x')", "(4,'d')", "(5,'e')", "(6,'f')", "(7,'g')", "(8,'h')", "(9,'i')")
+	defer func() {
+		_ = to.Close()
+	}()
+	d := NewDiffer(s, s)
+	if err := d.DiffTable("t1", nil); err != errNoDB {
+		t.Fatal(err)
+	}
+	d.fromDB, d.toDB = from, to
+	d.fromType, d.toType = db2go.SQLITE, db2go.SQLITE
+	// from分成[1,2]，[3,5]，[6]，to在每个主键范围内比较，from没有了之后to剩下的分块读取
+	d.SetChunk(2)
+	var result []string
+	err := d.DiffTable("t1", func(d *RowDiff) error {
+		result = append(result, d.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(result, "\n") != `row-changed t1(id="2"): name "b" -> "x"
+row-removed t1(id="3")
+row-added t1(id="4")
+row-added t1(id="7")
+row-added t1(id="8")
+row-added t1(id="9")` {
+		t.Fatal(strings.Join(result, "\n"))
+	}
+}
@@ -0,0 +1,65 @@
+package datadiff
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"strings"
+)
+
+// 在from执行后，这一行和to相同的sql，schema是from的结构
+func ReconcileSQL(schema *db2go.Schema, d *RowDiff) (string, error) {
+	t := schema.GetTable(d.Table)
+	if t == nil {
+		return "", fmt.Errorf("table '%s' not found", d.Table)
+	}
+	dbType := schema.DBType()
+	var where []string
+	for _, k := range d.keys {
+		where = append(where, fmt.Sprintf("%s=%s", db2go.QuoteName(dbType, k), literal(dbType, t.GetColumn(k), d.Key[k])))
+	}
+	table := db2go.QuoteName(dbType, t.Name())
+	switch d.Type {
+	case RowAdded:
+		var names, values []string
+		for _, c := range t.Columns() {
+			v, ok := d.Row[c.Name()]
+			if !ok {
+				continue
+			}
+			names = append(names, db2go.QuoteName(dbType, c.Name()))
+			values = append(values, literal(dbType, c, v))
+		}
+		return fmt.Sprintf("insert into %s(%s) values(%s);", table, strings.Join(names, ","), strings.Join(values, ",")), nil
+	case RowRemoved:
+		return fmt.Sprintf("delete from %s where %s;", table, strings.Join(where, " and ")), nil
+	case RowChanged:
+		var set []string
+		for _, c := range d.Columns {
+			set = append(set, fmt.Sprintf("%s=%s", db2go.QuoteName(dbType, c.Column), literal(dbType, t.GetColumn(c.Column), c.To)))
+		}
+		return fmt.Sprintf("update %s set %s where %s;", table, strings.Join(set, ","), strings.Join(where, " and ")), nil
+	default:
+		return "", fmt.Errorf("unknown row diff type '%s'", d.Type)
+	}
+}
+
+// 值的sql常量，数字不加引号，二进制使用X'hex'
+func literal(dbType string, c *db2go.Column, v interface{}) string {
+	if v == nil {
+		return "NULL"
+	}
+	s := fmt.Sprint(v)
+	if c == nil {
+		return db2go.QuoteString(dbType, s)
+	}
+	switch c.GoType() {
+	case "int8", "int16", "int32", "int", "int64", "uint8", "uint16", "uint32", "uint", "uint64", "float32", "float64",
+		"sql.NullInt32", "sql.NullInt64", "sql.NullFloat64":
+		return s
+	case "[]byte":
+		return "X'" + hex.EncodeToString([]byte(s)) + "'"
+	default:
+		return db2go.QuoteString(dbType, s)
+	}
+}
@@ -2,17 +2,30 @@ package db2go
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const (
-	MYSQL = "mysql"
+	MYSQL    = "mysql"
+	POSTGRES = "postgres"
+	SQLITE   = "sqlite3"
 )
 
 var (
-	schemaFunc = make(map[string]func(string) (*Schema, error))
-	goTypeFunc = make(map[string]func(string) string)
-	driver     = make(map[string]string)
+	errNoDB = errors.New("schema has no database connection")
+)
+
+var (
+	schemaFunc      = make(map[string]func(*sql.DB) (*Schema, error))
+	goTypeFunc      = make(map[string]func(string) string)
+	driver          = make(map[string]string)
+	quoteNameFunc   = make(map[string]func(string) string)
+	quoteStringFunc = make(map[string]func(string) string)
+	placeholderFunc = make(map[string]func(int) string)
 )
 
 // 驱动包，比如"github.com/go-sql-driver/mysql"
@@ -20,13 +33,71 @@ func DriverPkg(dbType string) string {
 	return driver[dbType]
 }
 
-// 读取数据库结构
+// 给表名和列名加上引号，比如mysql的`name`
+func QuoteName(dbType, name string) string {
+	f, o := quoteNameFunc[dbType]
+	if !o {
+		return name
+	}
+	return f(name)
+}
+
+// 字符串常量，加上引号并转义特殊字符
+func QuoteString(dbType, s string) string {
+	f, o := quoteStringFunc[dbType]
+	if !o {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	return f(s)
+}
+
+// sql的第i个（从1开始）参数占位符，比如mysql的?
+func Placeholder(dbType string, i int) string {
+	f, o := placeholderFunc[dbType]
+	if !o {
+		return "?"
+	}
+	return f(i)
+}
+
+// 读取数据库结构，返回的Schema持有打开的连接池，使用完需要Close
+// dbUrl使用ParseDSN解析，错误信息中不会有密码
 func ReadSchema(dbType, dbUrl string) (*Schema, error) {
 	f, o := schemaFunc[dbType]
 	if !o {
 		return nil, fmt.Errorf("unsupported db '%s'", dbType)
 	}
-	return f(dbUrl)
+	dsn, err := ParseDSN(dbType, dbUrl)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(dbType, dsn.String())
+	if err != nil {
+		return nil, &DSNError{DSN: dsn.Redacted(), Err: err}
+	}
+	s, err := f(db)
+	if err != nil {
+		_ = db.Close()
+		return nil, &DSNError{DSN: dsn.Redacted(), Err: err}
+	}
+	s.dbUrl = dsn.String()
+	s.db = db
+	s.closeDB = true
+	return s, nil
+}
+
+// 使用外部的连接池读取数据库结构，Schema.Close不会关闭db
+func ReadSchemaDB(dbType string, db *sql.DB) (*Schema, error) {
+	f, o := schemaFunc[dbType]
+	if !o {
+		return nil, fmt.Errorf("unsupported db '%s'", dbType)
+	}
+	s, err := f(db)
+	if err != nil {
+		return nil, err
+	}
+	s.db = db
+	return s, nil
 }
 
 // 返回go数据类型
@@ -40,10 +111,12 @@ func DBTypeToGo(dbType, dataType string) string {
 
 // 数据库结构
 type Schema struct {
-	dbUrl  string
-	dbType string
-	name   string   // 名称
-	table  []*Table // 所有的表
+	dbUrl   string
+	dbType  string
+	name    string   // 名称
+	table   []*Table // 所有的表
+	db      *sql.DB  // 连接池
+	closeDB bool     // Close时是否关闭db，外部传入的不关闭
 }
 
 func (s *Schema) GetTable(name string) *Table {
@@ -55,6 +128,51 @@ func (s *Schema) GetTable(name string) *Table {
 	return nil
 }
 
+// 按外键依赖排序，被引用的表在前面，自引用忽略，有循环引用的表放在最后
+func (s *Schema) SortedTables() []*Table {
+	// 每个表引用的其他表
+	deps := make(map[*Table]map[*Table]bool)
+	for _, t := range s.table {
+		deps[t] = make(map[*Table]bool)
+		for _, c := range t.column {
+			if c.foreignTable != nil && c.foreignTable.table != nil && c.foreignTable.table != t {
+				deps[t][c.foreignTable.table] = true
+			}
+		}
+	}
+	done := make(map[*Table]bool)
+	sorted := make([]*Table, 0, len(s.table))
+	for len(sorted) < len(s.table) {
+		n := len(sorted)
+		for _, t := range s.table {
+			if done[t] {
+				continue
+			}
+			ready := true
+			for d := range deps[t] {
+				if !done[d] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				done[t] = true
+				sorted = append(sorted, t)
+			}
+		}
+		// 循环引用
+		if n == len(sorted) {
+			for _, t := range s.table {
+				if !done[t] {
+					done[t] = true
+					sorted = append(sorted, t)
+				}
+			}
+		}
+	}
+	return sorted
+}
+
 func (s *Schema) DBType() string {
 	return s.dbType
 }
@@ -67,32 +185,78 @@ func (s *Schema) Tables() []*Table {
 	return s.table
 }
 
+// 连接池，没有则返回nil
+func (s *Schema) DB() *sql.DB {
+	return s.db
+}
+
+// 关闭ReadSchema打开的连接池
+func (s *Schema) Close() error {
+	if s.db == nil || !s.closeDB {
+		return nil
+	}
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
+
 // 测试
 func (s *Schema) TestSQL(prepare string) error {
-	db, err := sql.Open(s.dbType, s.dbUrl)
-	if err != nil {
-		return err
+	if s.db == nil {
+		return errNoDB
 	}
-	defer func() {
-		_ = db.Close()
-	}()
-	_, err = db.Prepare(prepare)
+	stmt, err := s.db.Prepare(prepare)
 	if err != nil {
 		return err
 	}
-	return nil
+	return stmt.Close()
 }
 
 // 数据库表
 type Table struct {
-	name   string
-	column []*Column
+	name    string
+	comment string // 注释
+	column  []*Column
+	index   []*Index    // 索引
+	stats   *TableStats // 统计信息
 }
 
 func (t *Table) Name() string {
 	return t.name
 }
 
+func (t *Table) Comment() string {
+	return t.comment
+}
+
+func (t *Table) Indexes() []*Index {
+	return t.index
+}
+
+func (t *Table) GetIndex(name string) *Index {
+	for _, i := range t.index {
+		if i.name == name {
+			return i
+		}
+	}
+	return nil
+}
+
+// 是否有以column开头的索引，可以用于查询column
+func (t *Table) HasIndexOn(column *Column) bool {
+	for _, i := range t.index {
+		if len(i.column) > 0 && i.column[0] == column {
+			return true
+		}
+	}
+	return false
+}
+
+// 表的统计信息，读取结构时一起读取
+func (t *Table) Stats() *TableStats {
+	return t.stats
+}
+
 func (t *Table) Columns() []*Column {
 	return t.column
 }
@@ -151,10 +315,15 @@ type Column struct {
 	nullable      bool          // NULL值
 	defaultValue  string        // 默认值
 	foreignTable  *ForeignTable // 引用表
+	comment       string        // 注释
 }
 
+// 没有注册类型转换的数据库，比如postgres和sqlite，当作string
 func (c *Column) GoType() string {
-	typ := goTypeFunc[c.dbType](c._type)
+	typ := "string"
+	if f, ok := goTypeFunc[c.dbType]; ok {
+		typ = f(c._type)
+	}
 	if c.nullable {
 		switch typ {
 		case "int8", "int16", "int32", "uint8", "uint16", "uint32":
@@ -163,6 +332,9 @@ func (c *Column) GoType() string {
 			return "sql.NullInt64"
 		case "float32", "float64":
 			return "sql.NullFloat64"
+		case "[]byte":
+			// nil表示NULL
+			return typ
 		default:
 			return "sql.NullString"
 		}
@@ -178,6 +350,61 @@ func (c *Column) Type() string {
 	return c._type
 }
 
+// 不带参数的类型，小写，比如"varchar(32)"返回"varchar"，"int unsigned"返回"int"
+func (c *Column) DataType() string {
+	typ := strings.ToLower(c._type)
+	if i := strings.IndexByte(typ, '('); i >= 0 {
+		typ = typ[:i]
+	}
+	typ = strings.TrimSpace(typ)
+	if i := strings.IndexByte(typ, ' '); i >= 0 {
+		typ = typ[:i]
+	}
+	return typ
+}
+
+// 类型的参数，比如"decimal(10,5)"返回["10","5"]，"enum('a','b')"返回["a","b"]
+func (c *Column) TypeArgs() []string {
+	i := strings.IndexByte(c._type, '(')
+	j := strings.LastIndexByte(c._type, ')')
+	if i < 0 || j < i {
+		return nil
+	}
+	var args []string
+	var arg strings.Builder
+	quoted := false
+	s := c._type[i+1 : j]
+	for k := 0; k < len(s); k++ {
+		switch {
+		case s[k] == '\'' && quoted && k+1 < len(s) && s[k+1] == '\'':
+			arg.WriteByte('\'')
+			k++
+		case s[k] == '\'':
+			quoted = !quoted
+		case s[k] == ',' && !quoted:
+			args = append(args, strings.TrimSpace(arg.String()))
+			arg.Reset()
+		default:
+			arg.WriteByte(s[k])
+		}
+	}
+	return append(args, strings.TrimSpace(arg.String()))
+}
+
+// 类型的长度，比如"varchar(32)"返回32，没有返回0
+func (c *Column) Length() int {
+	args := c.TypeArgs()
+	if len(args) < 1 {
+		return 0
+	}
+	n, _ := strconv.Atoi(args[0])
+	return n
+}
+
+func (c *Column) IsUnsigned() bool {
+	return strings.Contains(strings.ToLower(c._type), "unsigned")
+}
+
 func (c *Column) IsPrimaryKey() bool {
 	return c.primaryKey
 }
@@ -206,6 +433,131 @@ func (c *Column) DefaultValue() string {
 	return c.defaultValue
 }
 
+func (c *Column) Comment() string {
+	return c.comment
+}
+
+// 表索引
+type Index struct {
+	name    string
+	_type   string    // BTREE/HASH/FULLTEXT...
+	unique  bool      // 唯一
+	primary bool      // 主键
+	column  []*Column // 按索引中的顺序
+}
+
+// 创建索引，用于没有从数据库读取索引信息的时候
+func NewIndex(name string, unique, primary bool, columns ...*Column) *Index {
+	return &Index{name: name, unique: unique, primary: primary, column: columns}
+}
+
+func (i *Index) Name() string {
+	return i.name
+}
+
+func (i *Index) Type() string {
+	return i._type
+}
+
+func (i *Index) IsUnique() bool {
+	return i.unique
+}
+
+func (i *Index) IsPrimary() bool {
+	return i.primary
+}
+
+func (i *Index) Columns() []*Column {
+	return i.column
+}
+
+// 表的统计信息，行数和大小都是数据库的估算值
+type TableStats struct {
+	rows          int64        // 估算行数
+	dataLength    int64        // 数据大小
+	indexLength   int64        // 索引大小
+	autoIncrement int64        // 下一个自增值，0表示没有
+	createTime    time.Time    // 创建时间
+	updateTime    time.Time    // 更新时间，零值表示未知
+	partition     []*Partition // 分区
+}
+
+func (s *TableStats) Rows() int64 {
+	return s.rows
+}
+
+func (s *TableStats) DataLength() int64 {
+	return s.dataLength
+}
+
+func (s *TableStats) IndexLength() int64 {
+	return s.indexLength
+}
+
+// 数据和索引的总大小
+func (s *TableStats) TotalLength() int64 {
+	return s.dataLength + s.indexLength
+}
+
+func (s *TableStats) AutoIncrement() int64 {
+	return s.autoIncrement
+}
+
+func (s *TableStats) CreateTime() time.Time {
+	return s.createTime
+}
+
+func (s *TableStats) UpdateTime() time.Time {
+	return s.updateTime
+}
+
+func (s *TableStats) IsPartitioned() bool {
+	return len(s.partition) > 0
+}
+
+func (s *TableStats) Partitions() []*Partition {
+	return s.partition
+}
+
+// 表分区
+type Partition struct {
+	name        string // 名称
+	method      string // 分区方式，RANGE/LIST/HASH/KEY...
+	expression  string // 分区表达式
+	description string // 分区的值，比如RANGE的上限
+	rows        int64  // 估算行数
+	dataLength  int64  // 数据大小
+	indexLength int64  // 索引大小
+}
+
+func (p *Partition) Name() string {
+	return p.name
+}
+
+func (p *Partition) Method() string {
+	return p.method
+}
+
+func (p *Partition) Expression() string {
+	return p.expression
+}
+
+func (p *Partition) Description() string {
+	return p.description
+}
+
+func (p *Partition) Rows() int64 {
+	return p.rows
+}
+
+func (p *Partition) DataLength() int64 {
+	return p.dataLength
+}
+
+func (p *Partition) IndexLength() int64 {
+	return p.indexLength
+}
+
 type ForeignTable struct {
 	table  *Table
 	column *Column
@@ -0,0 +1,118 @@
+package db2go
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+var (
+	explainFunc = make(map[string]func(*sql.DB, string, ...interface{}) (*Explain, error))
+)
+
+// sql的执行计划
+type Explain struct {
+	Table          []*ExplainTable `json:"table"`          // 访问的表，按执行顺序
+	Cost           float64         `json:"cost"`           // 估算的代价
+	UsingFilesort  bool            `json:"usingFilesort"`  // 需要额外排序
+	UsingTemporary bool            `json:"usingTemporary"` // 需要临时表
+}
+
+// 执行计划中对一个表的访问
+type ExplainTable struct {
+	Table          string   `json:"table"`          // 表名，可能是别名或者<derived2>这样的临时表
+	AccessType     string   `json:"accessType"`     // 访问方式，ALL/index/range/ref/eq_ref/const...
+	PossibleKeys   []string `json:"possibleKeys"`   // 可以使用的索引
+	Key            string   `json:"key"`            // 实际使用的索引
+	UsedKeyParts   []string `json:"usedKeyParts"`   // 使用了索引的哪些列
+	Rows           int64    `json:"rows"`           // 估算扫描的行数
+	TableRows      int64    `json:"tableRows"`      // 表的估算行数，来自Table.Stats()
+	Filtered       float64  `json:"filtered"`       // 条件过滤后剩下的百分比
+	Condition      string   `json:"condition"`      // 附加的过滤条件
+	UsingIndex     bool     `json:"usingIndex"`     // 覆盖索引
+	UsingFilesort  bool     `json:"usingFilesort"`  // 需要额外排序
+	UsingTemporary bool     `json:"usingTemporary"` // 需要临时表
+}
+
+// 执行计划的问题
+type ExplainWarning struct {
+	Table   string `json:"table"`
+	Message string `json:"message"`
+}
+
+func (w *ExplainWarning) String() string {
+	if w.Table == "" {
+		return w.Message
+	}
+	return fmt.Sprintf("table '%s': %s", w.Table, w.Message)
+}
+
+// 是否全表扫描
+func (t *ExplainTable) IsFullTableScan() bool {
+	return t.AccessType == "ALL"
+}
+
+// 是否全索引扫描
+func (t *ExplainTable) IsFullIndexScan() bool {
+	return t.AccessType == "index"
+}
+
+// 是否没有使用索引
+func (t *ExplainTable) IsMissingIndex() bool {
+	return t.Key == "" && t.AccessType != "system" && t.AccessType != "const"
+}
+
+// 找出执行计划的问题，表的估算行数小于minRows的忽略
+func (e *Explain) Warnings(minRows int64) []*ExplainWarning {
+	var ws []*ExplainWarning
+	for _, t := range e.Table {
+		rows := t.Rows
+		if t.TableRows > rows {
+			rows = t.TableRows
+		}
+		if rows < minRows {
+			continue
+		}
+		// 扫描方式只报一个，filesort和临时表另外报
+		if t.IsFullTableScan() {
+			if len(t.PossibleKeys) > 0 {
+				ws = append(ws, &ExplainWarning{t.Table, fmt.Sprintf("full table scan of %d rows, possible keys %v not used", rows, t.PossibleKeys)})
+			} else {
+				ws = append(ws, &ExplainWarning{t.Table, fmt.Sprintf("full table scan of %d rows, no usable index", rows)})
+			}
+		} else if t.IsFullIndexScan() {
+			ws = append(ws, &ExplainWarning{t.Table, fmt.Sprintf("full index scan of %d rows on key '%s'", rows, t.Key)})
+		} else if t.IsMissingIndex() {
+			ws = append(ws, &ExplainWarning{t.Table, fmt.Sprintf("no index used, %d rows", rows)})
+		}
+		if t.UsingFilesort {
+			ws = append(ws, &ExplainWarning{t.Table, "using filesort"})
+		}
+		if t.UsingTemporary {
+			ws = append(ws, &ExplainWarning{t.Table, "using temporary table"})
+		}
+	}
+	return ws
+}
+
+// 分析sql的执行计划，args是sql的参数
+func (s *Schema) Explain(query string, args ...interface{}) (*Explain, error) {
+	f, o := explainFunc[s.dbType]
+	if !o {
+		return nil, fmt.Errorf("unsupported db '%s'", s.dbType)
+	}
+	if s.db == nil {
+		return nil, errNoDB
+	}
+	e, err := f(s.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	// 补充表的估算行数
+	for _, t := range e.Table {
+		table := s.GetTable(t.Table)
+		if table != nil && table.stats != nil {
+			t.TableRows = table.stats.rows
+		}
+	}
+	return e, nil
+}
@@ -0,0 +1,59 @@
+package db2go
+
+import "testing"
+
+func TestMysqlParseExplain(t *testing.T) {
+	e, err := mysqlParseExplain([]byte(`{
+  "query_block": {
+    "select_id": 1,
+    "cost_info": {"query_cost": "2.40"},
+    "ordering_operation": {
+      "using_temporary_table": true,
+      "using_filesort": true,
+      "nested_loop": [
+        {
+          "table": {
+            "table_name": "t3",
+            "access_type": "ALL",
+            "possible_keys": ["t3_t1_id_t2_id_uindex"],
+            "rows_examined_per_scan": 5000,
+            "filtered": "100.00"
+          }
+        },
+        {
+          "table": {
+            "table_name": "t1",
+            "access_type": "eq_ref",
+            "possible_keys": ["PRIMARY"],
+            "key": "PRIMARY",
+            "used_key_parts": ["id"],
+            "rows_examined_per_scan": 1,
+            "filtered": 100,
+            "using_index": true
+          }
+        }
+      ]
+    }
+  }
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Cost != 2.4 || !e.UsingFilesort || !e.UsingTemporary || len(e.Table) != 2 {
+		t.FailNow()
+	}
+	t3, t1 := e.Table[0], e.Table[1]
+	if t3.Table != "t3" || !t3.IsFullTableScan() || !t3.IsMissingIndex() || !t3.UsingFilesort || t3.Rows != 5000 {
+		t.FailNow()
+	}
+	if t1.Table != "t1" || t1.Key != "PRIMARY" || t1.IsMissingIndex() || !t1.UsingIndex || t1.Filtered != 100 {
+		t.FailNow()
+	}
+	ws := e.Warnings(1000)
+	if len(ws) != 3 || ws[0].Table != "t3" || ws[1].Message != "using filesort" || ws[2].Message != "using temporary table" {
+		t.FailNow()
+	}
+	if len(e.Warnings(10000)) != 0 {
+		t.FailNow()
+	}
+}
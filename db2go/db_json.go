@@ -0,0 +1,252 @@
+package db2go
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Schema的json格式，用于保存快照和离线使用
+type schemaJSON struct {
+	DBType string       `json:"dbType"`
+	Name   string       `json:"name"`
+	Tables []*tableJSON `json:"tables"`
+}
+
+type tableJSON struct {
+	Name    string        `json:"name"`
+	Comment string        `json:"comment,omitempty"`
+	Columns []*columnJSON `json:"columns"`
+	Indexes []*indexJSON  `json:"indexes,omitempty"`
+	Stats   *statsJSON    `json:"stats,omitempty"`
+}
+
+type columnJSON struct {
+	Name          string          `json:"name"`
+	Type          string          `json:"type"`
+	GoType        string          `json:"goType,omitempty"`
+	PrimaryKey    bool            `json:"primaryKey,omitempty"`
+	AutoIncrement bool            `json:"autoIncrement,omitempty"`
+	Unique        bool            `json:"unique,omitempty"`
+	MulUnique     bool            `json:"mulUnique,omitempty"`
+	Nullable      bool            `json:"nullable,omitempty"`
+	Default       string          `json:"default,omitempty"`
+	Comment       string          `json:"comment,omitempty"`
+	ForeignKey    *foreignKeyJSON `json:"foreignKey,omitempty"`
+}
+
+type foreignKeyJSON struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+}
+
+type indexJSON struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type,omitempty"`
+	Unique  bool     `json:"unique,omitempty"`
+	Primary bool     `json:"primary,omitempty"`
+	Columns []string `json:"columns"`
+}
+
+type statsJSON struct {
+	Rows          int64            `json:"rows"`
+	DataLength    int64            `json:"dataLength"`
+	IndexLength   int64            `json:"indexLength"`
+	AutoIncrement int64            `json:"autoIncrement,omitempty"`
+	CreateTime    *time.Time       `json:"createTime,omitempty"`
+	UpdateTime    *time.Time       `json:"updateTime,omitempty"`
+	Partitions    []*partitionJSON `json:"partitions,omitempty"`
+}
+
+type partitionJSON struct {
+	Name        string `json:"name"`
+	Method      string `json:"method"`
+	Expression  string `json:"expression,omitempty"`
+	Description string `json:"description,omitempty"`
+	Rows        int64  `json:"rows"`
+	DataLength  int64  `json:"dataLength"`
+	IndexLength int64  `json:"indexLength"`
+}
+
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	j := &schemaJSON{DBType: s.dbType, Name: s.name, Tables: make([]*tableJSON, 0, len(s.table))}
+	for _, t := range s.table {
+		j.Tables = append(j.Tables, t.toJSON())
+	}
+	return json.Marshal(j)
+}
+
+func (t *Table) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.toJSON())
+}
+
+func (t *Table) toJSON() *tableJSON {
+	j := &tableJSON{Name: t.name, Comment: t.comment, Columns: make([]*columnJSON, 0, len(t.column))}
+	for _, c := range t.column {
+		cj := &columnJSON{
+			Name:          c.name,
+			Type:          c._type,
+			PrimaryKey:    c.primaryKey,
+			AutoIncrement: c.autoIncrement,
+			Unique:        c.unique,
+			MulUnique:     c.mulUnique,
+			Nullable:      c.nullable,
+			Default:       c.defaultValue,
+			Comment:       c.comment,
+		}
+		if _, ok := goTypeFunc[c.dbType]; ok {
+			cj.GoType = c.GoType()
+		}
+		if c.foreignTable != nil && c.foreignTable.table != nil && c.foreignTable.column != nil {
+			cj.ForeignKey = &foreignKeyJSON{Table: c.foreignTable.table.name, Column: c.foreignTable.column.name}
+		}
+		j.Columns = append(j.Columns, cj)
+	}
+	for _, i := range t.index {
+		ij := &indexJSON{Name: i.name, Type: i._type, Unique: i.unique, Primary: i.primary}
+		for _, c := range i.column {
+			ij.Columns = append(ij.Columns, c.name)
+		}
+		j.Indexes = append(j.Indexes, ij)
+	}
+	if t.stats != nil {
+		sj := &statsJSON{
+			Rows:          t.stats.rows,
+			DataLength:    t.stats.dataLength,
+			IndexLength:   t.stats.indexLength,
+			AutoIncrement: t.stats.autoIncrement,
+		}
+		if !t.stats.createTime.IsZero() {
+			sj.CreateTime = &t.stats.createTime
+		}
+		if !t.stats.updateTime.IsZero() {
+			sj.UpdateTime = &t.stats.updateTime
+		}
+		for _, p := range t.stats.partition {
+			sj.Partitions = append(sj.Partitions, &partitionJSON{
+				Name:        p.name,
+				Method:      p.method,
+				Expression:  p.expression,
+				Description: p.description,
+				Rows:        p.rows,
+				DataLength:  p.dataLength,
+				IndexLength: p.indexLength,
+			})
+		}
+		j.Stats = sj
+	}
+	return j
+}
+
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	var j schemaJSON
+	err := json.Unmarshal(data, &j)
+	if err != nil {
+		return err
+	}
+	s.dbType = j.DBType
+	s.name = j.Name
+	s.table = nil
+	// 先创建所有的表和列，再设置外键和索引
+	for _, tj := range j.Tables {
+		t := &Table{name: tj.Name, comment: tj.Comment, stats: new(TableStats)}
+		for _, cj := range tj.Columns {
+			t.column = append(t.column, &Column{
+				dbType:        j.DBType,
+				name:          cj.Name,
+				_type:         cj.Type,
+				primaryKey:    cj.PrimaryKey,
+				autoIncrement: cj.AutoIncrement,
+				unique:        cj.Unique,
+				mulUnique:     cj.MulUnique,
+				nullable:      cj.Nullable,
+				defaultValue:  cj.Default,
+				comment:       cj.Comment,
+			})
+		}
+		if sj := tj.Stats; sj != nil {
+			t.stats.rows = sj.Rows
+			t.stats.dataLength = sj.DataLength
+			t.stats.indexLength = sj.IndexLength
+			t.stats.autoIncrement = sj.AutoIncrement
+			if sj.CreateTime != nil {
+				t.stats.createTime = *sj.CreateTime
+			}
+			if sj.UpdateTime != nil {
+				t.stats.updateTime = *sj.UpdateTime
+			}
+			for _, pj := range sj.Partitions {
+				t.stats.partition = append(t.stats.partition, &Partition{
+					name:        pj.Name,
+					method:      pj.Method,
+					expression:  pj.Expression,
+					description: pj.Description,
+					rows:        pj.Rows,
+					dataLength:  pj.DataLength,
+					indexLength: pj.IndexLength,
+				})
+			}
+		}
+		s.table = append(s.table, t)
+	}
+	for i, tj := range j.Tables {
+		t := s.table[i]
+		for k, cj := range tj.Columns {
+			if cj.ForeignKey == nil {
+				continue
+			}
+			ref := s.GetTable(cj.ForeignKey.Table)
+			if ref == nil {
+				return fmt.Errorf("table '%s' column '%s' references unknown table '%s'", t.name, cj.Name, cj.ForeignKey.Table)
+			}
+			refColumn := ref.GetColumn(cj.ForeignKey.Column)
+			if refColumn == nil {
+				return fmt.Errorf("table '%s' column '%s' references unknown column '%s.%s'", t.name, cj.Name, ref.name, cj.ForeignKey.Column)
+			}
+			t.column[k].foreignTable = &ForeignTable{table: ref, column: refColumn}
+		}
+		for _, ij := range tj.Indexes {
+			index := &Index{name: ij.Name, _type: ij.Type, unique: ij.Unique, primary: ij.Primary}
+			for _, name := range ij.Columns {
+				c := t.GetColumn(name)
+				if c == nil {
+					return fmt.Errorf("table '%s' index '%s' has unknown column '%s'", t.name, ij.Name, name)
+				}
+				index.column = append(index.column, c)
+			}
+			t.index = append(t.index, index)
+		}
+	}
+	return nil
+}
+
+// 读取WriteSchemaJSON保存的结构，没有数据库连接，不能TestSQL和Explain
+func ReadSchemaJSON(r io.Reader) (*Schema, error) {
+	s := new(Schema)
+	err := json.NewDecoder(r).Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// 读取WriteSchemaJSON保存的结构文件
+func ReadSchemaJSONFile(name string) (*Schema, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return ReadSchemaJSON(f)
+}
+
+// 以缩进的json格式保存结构
+func WriteSchemaJSON(w io.Writer, s *Schema) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
@@ -0,0 +1,40 @@
+package db2go
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestSchemaJSON(t *testing.T) {
+	s := testSchema()
+	var buf bytes.Buffer
+	err := WriteSchemaJSON(&buf, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "schema.json")
+	err = ioutil.WriteFile(file, buf.Bytes(), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := ReadSchemaJSONFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s2.Name() != s.Name() || s2.DBType() != s.DBType() || len(s2.Tables()) != len(s.Tables()) {
+		t.FailNow()
+	}
+	if changes := DiffSchema(s, s2); len(changes) != 0 {
+		t.Fatal(changes[0])
+	}
+	// 外键指向同一个结构中的表
+	c := s2.GetTable("t3").GetColumn("t1_id")
+	if c.ForeignTable().Table() != s2.GetTable("t1") || c.ForeignTable().Column() != s2.GetTable("t1").GetColumn("id") {
+		t.FailNow()
+	}
+	if i := s2.GetTable("t3").GetIndex("t3_t1_id_t2_uindex"); i == nil || len(i.Columns()) != 2 || i.Columns()[1] != s2.GetTable("t3").GetColumn("t2") {
+		t.FailNow()
+	}
+}
@@ -5,14 +5,26 @@ package db2go
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
-	_ "github.com/go-sql-driver/mysql"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func init() {
 	schemaFunc[MYSQL] = mysqlReadSchema
 	goTypeFunc[MYSQL] = mysqlGoType
+	explainFunc[MYSQL] = mysqlExplain
+	dsnFunc[MYSQL] = mysqlParseDSN
+	dsnPasswordFunc[MYSQL] = mysqlDSNPassword
+	ddlFunc[MYSQL] = mysqlTableDDL
+	quoteNameFunc[MYSQL] = mysqlQuoteName
+	quoteStringFunc[MYSQL] = mysqlQuoteString
+	placeholderFunc[MYSQL] = mysqlPlaceholder
 	driver[MYSQL] = "github.com/go-sql-driver/mysql"
 }
 
@@ -23,7 +35,14 @@ var (
 
 // 数据类型对应表
 func mysqlGoType(dataType string) string {
+	// column_type，比如"int(11) unsigned zerofill"，去掉参数和zerofill
 	dataType = strings.ToLower(dataType)
+	if i := strings.IndexByte(dataType, '('); i >= 0 {
+		if j := strings.LastIndexByte(dataType, ')'); j > i {
+			dataType = dataType[:i] + dataType[j+1:]
+		}
+	}
+	dataType = strings.Join(strings.Fields(strings.ReplaceAll(dataType, "zerofill", "")), " ")
 	switch dataType {
 	case "tinyint":
 		return "int8"
@@ -56,7 +75,7 @@ func mysqlGoType(dataType string) string {
 	case "date", "time", "year", "datetime", "timestamp":
 		return "string"
 	default:
-		if strings.HasPrefix(dataType, "binary") {
+		if strings.HasPrefix(dataType, "binary") || strings.HasPrefix(dataType, "varbinary") {
 			return "[]byte"
 		}
 		if strings.HasPrefix(dataType, "decimal") {
@@ -67,28 +86,29 @@ func mysqlGoType(dataType string) string {
 }
 
 // 读取数据库结构
-func mysqlReadSchema(dbUrl string) (*Schema, error) {
-	var err error
+func mysqlReadSchema(db *sql.DB) (*Schema, error) {
 	schema := new(Schema)
-	schema.dbUrl = dbUrl
 	schema.dbType = MYSQL
-	schema.name, err = mysqlParseSchemaName(dbUrl)
+	// 连接的数据库名称
+	var name sql.NullString
+	err := db.QueryRow("select database()").Scan(&name)
 	if err != nil {
 		return nil, err
 	}
-	// 打开数据库
-	db, err := sql.Open(MYSQL, dbUrl)
-	if err != nil {
-		return nil, err
+	if name.String == "" {
+		return nil, errEmptyDBName
 	}
-	defer func() {
-		_ = db.Close()
-	}()
+	schema.name = name.String
 	// 读取数据库所有表
 	err = mysqlReadSchemaTable(db, schema)
 	if err != nil {
 		return nil, err
 	}
+	// 读取表分区
+	err = mysqlReadSchemaPartition(db, schema)
+	if err != nil {
+		return nil, err
+	}
 	// 读取表所有列信息
 	for _, table := range schema.table {
 		err = mysqlReadSchemaTableColumn(db, schema, table)
@@ -100,24 +120,47 @@ func mysqlReadSchema(dbUrl string) (*Schema, error) {
 			return nil, err
 		}
 	}
+	// 读取索引
+	err = mysqlReadSchemaIndex(db, schema)
+	if err != nil {
+		return nil, err
+	}
 	return schema, nil
 }
 
-// 从连接字符串中解析出数据库名称
-func mysqlParseSchemaName(dbUrl string) (string, error) {
-	i := strings.Index(dbUrl, "/")
-	if i < 0 {
-		return "", errEmptyDBName
+// 使用驱动解析连接字符串，参数passwordFile表示从文件读取密码
+func mysqlParseDSN(dsn string) (*DSN, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
 	}
-	schema := dbUrl[i+1:]
-	i = strings.Index(schema, "?")
-	if i > 0 {
-		schema = schema[:i]
+	// 密码文件，不能传给驱动，否则会当成系统变量
+	if file, ok := cfg.Params["passwordFile"]; ok {
+		delete(cfg.Params, "passwordFile")
+		cfg.Passwd, err = ReadPasswordFile(file)
+		if err != nil {
+			return nil, err
+		}
 	}
-	if schema == "" {
-		return "", errEmptyDBName
+	d := new(DSN)
+	d.dbType = MYSQL
+	d.name = cfg.DBName
+	d.dsn = cfg.FormatDSN()
+	if cfg.Passwd != "" {
+		cfg.Passwd = "***"
 	}
-	return schema, nil
+	d.redacted = cfg.FormatDSN()
+	return d, nil
+}
+
+// 修改连接字符串中的密码
+func mysqlDSNPassword(dsn, password string) (string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	cfg.Passwd = password
+	return cfg.FormatDSN(), nil
 }
 
 // 读取数据库所有表
@@ -125,7 +168,14 @@ func mysqlReadSchemaTable(db *sql.DB, schema *Schema) error {
 	// sql
 	var str strings.Builder
 	str.WriteString("select ")
-	str.WriteString("table_name ")
+	str.WriteString("table_name,")
+	str.WriteString("table_rows,")
+	str.WriteString("data_length,")
+	str.WriteString("index_length,")
+	str.WriteString("auto_increment,")
+	str.WriteString("create_time,")
+	str.WriteString("update_time,")
+	str.WriteString("table_comment ")
 	str.WriteString("from ")
 	str.WriteString("information_schema.tables ")
 	str.WriteString("where ")
@@ -140,15 +190,224 @@ func mysqlReadSchemaTable(db *sql.DB, schema *Schema) error {
 		}
 		return nil
 	}
+	defer func() {
+		_ = rows.Close()
+	}()
 	// 循环读table
+	var tableRows, dataLength, indexLength, autoIncrement sql.NullInt64
+	var createTime, updateTime mysqlNullTime
+	var tableComment sql.NullString
 	for rows.Next() {
 		table := new(Table)
-		err = rows.Scan(&table.name)
+		err = rows.Scan(&table.name, &tableRows, &dataLength, &indexLength, &autoIncrement, &createTime, &updateTime, &tableComment)
 		if err != nil {
 			return err
 		}
+		// 视图这些值都是null
+		table.stats = &TableStats{
+			rows:          tableRows.Int64,
+			dataLength:    dataLength.Int64,
+			indexLength:   indexLength.Int64,
+			autoIncrement: autoIncrement.Int64,
+			createTime:    createTime.Time,
+			updateTime:    updateTime.Time,
+		}
+		table.comment = tableComment.String
 		schema.table = append(schema.table, table)
 	}
+	return rows.Err()
+}
+
+// 读取数据库所有表的分区
+func mysqlReadSchemaPartition(db *sql.DB, schema *Schema) error {
+	// sql
+	var str strings.Builder
+	str.WriteString("select ")
+	str.WriteString("table_name,")
+	str.WriteString("partition_name,")
+	str.WriteString("partition_method,")
+	str.WriteString("partition_expression,")
+	str.WriteString("partition_description,")
+	str.WriteString("table_rows,")
+	str.WriteString("data_length,")
+	str.WriteString("index_length ")
+	str.WriteString("from ")
+	str.WriteString("information_schema.partitions ")
+	str.WriteString("where ")
+	str.WriteString("table_schema='")
+	str.WriteString(schema.name)
+	str.WriteString("' ")
+	str.WriteString("and ")
+	str.WriteString("partition_name is not null ")
+	str.WriteString("order by table_name,partition_ordinal_position")
+	// 查询
+	rows, err := db.Query(str.String())
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return err
+		}
+		return nil
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	// 循环
+	var tableName, partitionName, partitionMethod, partitionExpression, partitionDescription sql.NullString
+	var tableRows, dataLength, indexLength sql.NullInt64
+	for rows.Next() {
+		err = rows.Scan(&tableName, &partitionName, &partitionMethod, &partitionExpression, &partitionDescription,
+			&tableRows, &dataLength, &indexLength)
+		if err != nil {
+			return err
+		}
+		table := schema.GetTable(tableName.String)
+		if table == nil {
+			continue
+		}
+		table.stats.partition = append(table.stats.partition, &Partition{
+			name:        partitionName.String,
+			method:      partitionMethod.String,
+			expression:  partitionExpression.String,
+			description: partitionDescription.String,
+			rows:        tableRows.Int64,
+			dataLength:  dataLength.Int64,
+			indexLength: indexLength.Int64,
+		})
+	}
+	return rows.Err()
+}
+
+// 读取数据库所有表的索引
+func mysqlReadSchemaIndex(db *sql.DB, schema *Schema) error {
+	// sql
+	var str strings.Builder
+	str.WriteString("select ")
+	str.WriteString("table_name,")
+	str.WriteString("index_name,")
+	str.WriteString("non_unique,")
+	str.WriteString("column_name,")
+	str.WriteString("index_type ")
+	str.WriteString("from ")
+	str.WriteString("information_schema.statistics ")
+	str.WriteString("where ")
+	str.WriteString("table_schema='")
+	str.WriteString(schema.name)
+	str.WriteString("' ")
+	str.WriteString("order by table_name,index_name,seq_in_index")
+	// 查询
+	rows, err := db.Query(str.String())
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return err
+		}
+		return nil
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	// 循环
+	var indexRows []*mysqlIndexRow
+	for rows.Next() {
+		var tableName, indexName, columnName, indexType sql.NullString
+		var nonUnique sql.NullInt64
+		err = rows.Scan(&tableName, &indexName, &nonUnique, &columnName, &indexType)
+		if err != nil {
+			return err
+		}
+		indexRows = append(indexRows, &mysqlIndexRow{
+			table:     tableName.String,
+			index:     indexName.String,
+			unique:    nonUnique.Int64 == 0,
+			column:    columnName.String,
+			indexType: indexType.String,
+		})
+	}
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+	mysqlAddIndex(schema, indexRows)
+	return nil
+}
+
+// information_schema.statistics的一行，索引的一列
+type mysqlIndexRow struct {
+	table     string
+	index     string
+	unique    bool
+	column    string
+	indexType string
+}
+
+// 添加表的索引，函数索引的部分没有列名，整个索引都忽略，
+// 否则(a, (lower(b)))会变成a的索引，唯一索引会变成a唯一
+func mysqlAddIndex(schema *Schema, rows []*mysqlIndexRow) {
+	functional := make(map[[2]string]bool)
+	for _, r := range rows {
+		if r.column == "" {
+			functional[[2]string{r.table, r.index}] = true
+		}
+	}
+	for _, r := range rows {
+		if functional[[2]string{r.table, r.index}] {
+			continue
+		}
+		table := schema.GetTable(r.table)
+		if table == nil {
+			continue
+		}
+		column := table.GetColumn(r.column)
+		if column == nil {
+			continue
+		}
+		index := table.GetIndex(r.index)
+		if index == nil {
+			index = &Index{
+				name:    r.index,
+				_type:   r.indexType,
+				unique:  r.unique,
+				primary: strings.ToLower(r.index) == "primary",
+			}
+			table.index = append(table.index, index)
+		}
+		index.column = append(index.column, column)
+	}
+}
+
+// 读取时间，兼容连接字符串有没有parseTime=true
+type mysqlNullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+func (t *mysqlNullTime) Scan(value interface{}) error {
+	t.Time, t.Valid = time.Time{}, false
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		t.Time = v
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	default:
+		return fmt.Errorf("can't scan %T into time", value)
+	}
+	t.Valid = true
+	return nil
+}
+
+func (t *mysqlNullTime) parse(s string) error {
+	if s == "" || strings.HasPrefix(s, "0000-00-00") {
+		return nil
+	}
+	var err error
+	t.Time, err = time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+	if err != nil {
+		return err
+	}
+	t.Valid = true
 	return nil
 }
 
@@ -162,7 +421,8 @@ func mysqlReadSchemaTableColumn(db *sql.DB, schema *Schema, table *Table) error
 	str.WriteString("column_key,")
 	str.WriteString("column_default,")
 	str.WriteString("is_nullable,")
-	str.WriteString("extra ")
+	str.WriteString("extra,")
+	str.WriteString("column_comment ")
 	str.WriteString("from ")
 	str.WriteString("information_schema.columns ")
 	str.WriteString("where ")
@@ -182,9 +442,9 @@ func mysqlReadSchemaTableColumn(db *sql.DB, schema *Schema, table *Table) error
 		return nil
 	}
 	// 循环
-	var columnName, columnType, columnKey, columnDefault, isNullable, extra sql.NullString
+	var columnName, columnType, columnKey, columnDefault, isNullable, extra, columnComment sql.NullString
 	for rows.Next() {
-		err = rows.Scan(&columnName, &columnType, &columnKey, &columnDefault, &isNullable, &extra)
+		err = rows.Scan(&columnName, &columnType, &columnKey, &columnDefault, &isNullable, &extra, &columnComment)
 		if err != nil {
 			return err
 		}
@@ -193,9 +453,10 @@ func mysqlReadSchemaTableColumn(db *sql.DB, schema *Schema, table *Table) error
 			return errInvalidColumn
 		}
 		column := &Column{
-			dbType: schema.dbType,
-			name:   columnName.String,
-			_type:  columnType.String,
+			dbType:  schema.dbType,
+			name:    columnName.String,
+			_type:   columnType.String,
+			comment: columnComment.String,
 		}
 		// key
 		if columnKey.Valid {
@@ -293,3 +554,215 @@ func mysqlReadSchemaColumnMulAndReference(db *sql.DB, schema *Schema, table *Tab
 
 	return nil
 }
+
+// 使用EXPLAIN FORMAT=JSON分析sql
+func mysqlExplain(db *sql.DB, query string, args ...interface{}) (*Explain, error) {
+	var str string
+	err := db.QueryRow("explain format=json "+query, args...).Scan(&str)
+	if err != nil {
+		return nil, err
+	}
+	return mysqlParseExplain([]byte(str))
+}
+
+// 解析EXPLAIN FORMAT=JSON的结果
+func mysqlParseExplain(data []byte) (*Explain, error) {
+	var v map[string]interface{}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return nil, err
+	}
+	e := new(Explain)
+	if qb, ok := v["query_block"].(map[string]interface{}); ok {
+		if ci, ok := qb["cost_info"].(map[string]interface{}); ok {
+			e.Cost = mysqlExplainFloat(ci["query_cost"])
+		}
+	}
+	mysqlWalkExplain(e, v)
+	return e, nil
+}
+
+// 递归找出所有的table，返回这一层找到的table
+func mysqlWalkExplain(e *Explain, v interface{}) []*ExplainTable {
+	var tables []*ExplainTable
+	switch m := v.(type) {
+	case []interface{}:
+		for _, a := range m {
+			tables = append(tables, mysqlWalkExplain(e, a)...)
+		}
+	case map[string]interface{}:
+		for _, k := range mysqlSortedKeys(m) {
+			switch a := m[k].(type) {
+			case map[string]interface{}:
+				if k == "table" {
+					table := mysqlExplainTable(a)
+					e.Table = append(e.Table, table)
+					tables = append(tables, table)
+					// table里面还可能有子查询
+					for _, k2 := range mysqlSortedKeys(a) {
+						if k2 != "cost_info" {
+							mysqlWalkExplain(e, a[k2])
+						}
+					}
+					continue
+				}
+				tables = append(tables, mysqlWalkExplain(e, a)...)
+			case []interface{}:
+				tables = append(tables, mysqlWalkExplain(e, a)...)
+			}
+		}
+		// 排序，分组，去重都在第一个表上
+		filesort, _ := m["using_filesort"].(bool)
+		temporary, _ := m["using_temporary_table"].(bool)
+		if filesort {
+			e.UsingFilesort = true
+			if len(tables) > 0 {
+				tables[0].UsingFilesort = true
+			}
+		}
+		if temporary {
+			e.UsingTemporary = true
+			if len(tables) > 0 {
+				tables[0].UsingTemporary = true
+			}
+		}
+	}
+	return tables
+}
+
+// map是无序的，排序后遍历，保证表的顺序
+func mysqlSortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func mysqlExplainTable(m map[string]interface{}) *ExplainTable {
+	t := new(ExplainTable)
+	t.Table, _ = m["table_name"].(string)
+	t.AccessType, _ = m["access_type"].(string)
+	t.Key, _ = m["key"].(string)
+	t.PossibleKeys = mysqlExplainStrings(m["possible_keys"])
+	t.UsedKeyParts = mysqlExplainStrings(m["used_key_parts"])
+	t.Rows = int64(mysqlExplainFloat(m["rows_examined_per_scan"]))
+	t.Filtered = mysqlExplainFloat(m["filtered"])
+	t.Condition, _ = m["attached_condition"].(string)
+	t.UsingIndex, _ = m["using_index"].(bool)
+	return t
+}
+
+func mysqlExplainStrings(v interface{}) []string {
+	a, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	var ss []string
+	for _, s := range a {
+		if str, ok := s.(string); ok {
+			ss = append(ss, str)
+		}
+	}
+	return ss
+}
+
+// 有的版本是"100.00"，有的版本是100
+func mysqlExplainFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	}
+	return 0
+}
+
+// 参数占位符
+func mysqlPlaceholder(int) string {
+	return "?"
+}
+
+// 字符串常量，'xx'
+// 转义的字符和mysql_real_escape_string一样，结果不会有换行
+var mysqlStringReplacer = strings.NewReplacer(`\`, `\\`, "'", "''", "\x00", `\0`, "\n", `\n`, "\r", `\r`, "\x1a", `\Z`)
+
+func mysqlQuoteString(s string) string {
+	return "'" + mysqlStringReplacer.Replace(s) + "'"
+}
+
+func mysqlQuoteName(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func mysqlQuoteNames(columns []*Column) string {
+	var names []string
+	for _, c := range columns {
+		names = append(names, mysqlQuoteName(c.name))
+	}
+	return strings.Join(names, ",")
+}
+
+// mysql的CREATE TABLE
+func mysqlTableDDL(t *Table) string {
+	var lines []string
+	for _, c := range t.column {
+		var str strings.Builder
+		str.WriteString("  ")
+		str.WriteString(mysqlQuoteName(c.name))
+		str.WriteByte(' ')
+		str.WriteString(c._type)
+		if c.nullable {
+			str.WriteString(" NULL")
+		} else {
+			str.WriteString(" NOT NULL")
+		}
+		if c.defaultValue != "" {
+			str.WriteString(" DEFAULT ")
+			str.WriteString(ddlDefaultValue(c, mysqlQuoteString))
+		}
+		if c.autoIncrement {
+			str.WriteString(" AUTO_INCREMENT")
+		}
+		if c.comment != "" {
+			str.WriteString(" COMMENT ")
+			str.WriteString(mysqlQuoteString(c.comment))
+		}
+		lines = append(lines, str.String())
+	}
+	if pk := t.primaryKey(); len(pk) > 0 {
+		lines = append(lines, "  PRIMARY KEY ("+mysqlQuoteNames(pk)+")")
+	}
+	for _, i := range t.secondaryIndexes() {
+		switch {
+		case i.unique:
+			lines = append(lines, "  UNIQUE KEY "+mysqlQuoteName(i.name)+" ("+mysqlQuoteNames(i.column)+")")
+		case strings.EqualFold(i._type, "fulltext"):
+			lines = append(lines, "  FULLTEXT KEY "+mysqlQuoteName(i.name)+" ("+mysqlQuoteNames(i.column)+")")
+		default:
+			lines = append(lines, "  KEY "+mysqlQuoteName(i.name)+" ("+mysqlQuoteNames(i.column)+")")
+		}
+	}
+	for _, c := range t.column {
+		if c.foreignTable == nil || c.foreignTable.table == nil || c.foreignTable.column == nil {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+			mysqlQuoteName(t.name+"_"+c.name+"_fk"), mysqlQuoteName(c.name),
+			mysqlQuoteName(c.foreignTable.table.name), mysqlQuoteName(c.foreignTable.column.name)))
+	}
+	var str strings.Builder
+	str.WriteString("CREATE TABLE ")
+	str.WriteString(mysqlQuoteName(t.name))
+	str.WriteString(" (\n")
+	str.WriteString(strings.Join(lines, ",\n"))
+	str.WriteString("\n)")
+	if t.comment != "" {
+		str.WriteString(" COMMENT=")
+		str.WriteString(mysqlQuoteString(t.comment))
+	}
+	str.WriteString(";\n")
+	return str.String()
+}
@@ -0,0 +1,27 @@
+package db2go
+
+import "testing"
+
+func TestMySQLAddIndex(t *testing.T) {
+	s := testSchema()
+	t2 := s.GetTable("t2")
+	t2.index = nil
+	mysqlAddIndex(s, []*mysqlIndexRow{
+		{table: "t2", index: "PRIMARY", unique: true, column: "id", indexType: "BTREE"},
+		// 函数索引(name, (lower(name)))
+		{table: "t2", index: "t2_name_uindex", unique: true, column: "name", indexType: "BTREE"},
+		{table: "t2", index: "t2_name_uindex", unique: true, indexType: "BTREE"},
+		{table: "t2", index: "t2_price_index", column: "price", indexType: "BTREE"},
+		{table: "t2", index: "t2_price_index", column: "name", indexType: "BTREE"},
+		{table: "t5", index: "PRIMARY", unique: true, column: "id", indexType: "BTREE"},
+	})
+	if len(t2.index) != 2 || t2.GetIndex("t2_name_uindex") != nil {
+		t.Fatal(t2.index)
+	}
+	if i := t2.GetIndex("PRIMARY"); !i.IsPrimary() || len(i.Columns()) != 1 || i.Columns()[0].Name() != "id" {
+		t.Fatal(i.Columns())
+	}
+	if i := t2.GetIndex("t2_price_index"); i.Type() != "BTREE" || len(i.Columns()) != 2 || i.Columns()[1].Name() != "name" {
+		t.Fatal(i.Columns())
+	}
+}
@@ -0,0 +1,230 @@
+package db2go
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// postgres只用于作为目标数据库，比如生成DDL和复制数据，不能读取结构
+func init() {
+	dsnFunc[POSTGRES] = postgresParseDSN
+	dsnPasswordFunc[POSTGRES] = postgresDSNPassword
+	ddlFunc[POSTGRES] = postgresTableDDL
+	quoteNameFunc[POSTGRES] = postgresQuoteName
+	quoteStringFunc[POSTGRES] = postgresQuoteString
+	placeholderFunc[POSTGRES] = postgresPlaceholder
+	driver[POSTGRES] = "github.com/lib/pq"
+}
+
+func postgresPlaceholder(i int) string {
+	return "$" + strconv.Itoa(i)
+}
+
+func postgresQuoteName(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func postgresQuoteNames(columns []*Column) string {
+	var names []string
+	for _, c := range columns {
+		names = append(names, postgresQuoteName(c.name))
+	}
+	return strings.Join(names, ",")
+}
+
+func postgresQuoteString(s string) string {
+	s = strings.ReplaceAll(s, "'", "''")
+	// 有换行等字符时使用E''
+	if strings.ContainsAny(s, "\\\n\r\x00") {
+		s = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "\x00", "").Replace(s)
+		return "E'" + s + "'"
+	}
+	return "'" + s + "'"
+}
+
+// key=value格式的开头，值中可以有@和/，比如host=/var/run/postgresql
+var postgresKeyValueRegexp = regexp.MustCompile(`^\s*\w+\s*=`)
+
+// user:password@host/db?sslmode=disable，ParseURL去掉了postgres://，key=value格式不变
+func postgresParseDSN(dsn string) (*DSN, error) {
+	d := new(DSN)
+	d.dbType = POSTGRES
+	if postgresKeyValueRegexp.MatchString(dsn) {
+		d.dsn = dsn
+		d.redacted = RedactDSN(dsn)
+		for _, kv := range strings.Fields(dsn) {
+			if strings.HasPrefix(kv, "dbname=") {
+				d.name = strings.Trim(kv[len("dbname="):], "'")
+			}
+		}
+		return d, nil
+	}
+	if !strings.Contains(dsn, "://") {
+		dsn = "postgres://" + dsn
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	d.name = strings.TrimPrefix(u.Path, "/")
+	d.dsn = u.String()
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "***")
+	}
+	d.redacted = u.String()
+	return d, nil
+}
+
+func postgresDSNPassword(dsn, password string) (string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme == "" {
+		return "", fmt.Errorf("can't set password of key=value dsn")
+	}
+	u.User = url.UserPassword(u.User.Username(), password)
+	return u.String(), nil
+}
+
+// 列在postgres中的类型
+func postgresColumnType(c *Column) string {
+	if c.dbType == POSTGRES {
+		return c._type
+	}
+	args := c.TypeArgs()
+	switch c.DataType() {
+	case "tinyint", "smallint", "year":
+		if c.autoIncrement {
+			return "serial"
+		}
+		return "smallint"
+	case "mediumint", "int", "integer":
+		if c.autoIncrement {
+			if c.IsUnsigned() {
+				return "bigserial"
+			}
+			return "serial"
+		}
+		if c.IsUnsigned() {
+			return "bigint"
+		}
+		return "integer"
+	case "bigint":
+		if c.autoIncrement {
+			return "bigserial"
+		}
+		if c.IsUnsigned() {
+			return "numeric(20)"
+		}
+		return "bigint"
+	case "float", "real":
+		return "real"
+	case "double":
+		return "double precision"
+	case "decimal", "numeric":
+		if len(args) > 0 {
+			return "numeric(" + strings.Join(args, ",") + ")"
+		}
+		return "numeric"
+	case "char":
+		if len(args) > 0 {
+			return "char(" + args[0] + ")"
+		}
+		return "char(1)"
+	case "varchar":
+		if len(args) > 0 {
+			return "varchar(" + args[0] + ")"
+		}
+		return "text"
+	case "enum":
+		n := 1
+		for _, a := range args {
+			if len(a) > n {
+				n = len(a)
+			}
+		}
+		return "varchar(" + strconv.Itoa(n) + ")"
+	case "tinyblob", "blob", "mediumblob", "longblob", "binary", "varbinary", "bit":
+		return "bytea"
+	case "date":
+		return "date"
+	case "time":
+		return "time"
+	case "datetime", "timestamp":
+		return "timestamp"
+	case "json":
+		return "json"
+	default:
+		return "text"
+	}
+}
+
+// 索引的名称在postgres中是全局的，加上表名
+func ddlIndexName(t *Table, i *Index) string {
+	if strings.HasPrefix(i.name, t.name+"_") {
+		return i.name
+	}
+	return t.name + "_" + i.name
+}
+
+// postgres的CREATE TABLE，索引和注释是单独的语句
+func postgresTableDDL(t *Table) string {
+	var lines []string
+	for _, c := range t.column {
+		var str strings.Builder
+		str.WriteString("  ")
+		str.WriteString(postgresQuoteName(c.name))
+		str.WriteByte(' ')
+		typ := postgresColumnType(c)
+		str.WriteString(typ)
+		if c.nullable {
+			str.WriteString(" NULL")
+		} else {
+			str.WriteString(" NOT NULL")
+		}
+		if c.defaultValue != "" && !strings.HasSuffix(typ, "serial") {
+			str.WriteString(" DEFAULT ")
+			str.WriteString(ddlDefaultValue(c, postgresQuoteString))
+		}
+		lines = append(lines, str.String())
+	}
+	if pk := t.primaryKey(); len(pk) > 0 {
+		lines = append(lines, "  PRIMARY KEY ("+postgresQuoteNames(pk)+")")
+	}
+	for _, c := range t.column {
+		if c.foreignTable == nil || c.foreignTable.table == nil || c.foreignTable.column == nil {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+			postgresQuoteName(t.name+"_"+c.name+"_fk"), postgresQuoteName(c.name),
+			postgresQuoteName(c.foreignTable.table.name), postgresQuoteName(c.foreignTable.column.name)))
+	}
+	var str strings.Builder
+	str.WriteString("CREATE TABLE ")
+	str.WriteString(postgresQuoteName(t.name))
+	str.WriteString(" (\n")
+	str.WriteString(strings.Join(lines, ",\n"))
+	str.WriteString("\n);\n")
+	for _, i := range t.secondaryIndexes() {
+		// 全文索引不能直接转换
+		if strings.EqualFold(i._type, "fulltext") {
+			continue
+		}
+		if i.unique {
+			str.WriteString("CREATE UNIQUE INDEX ")
+		} else {
+			str.WriteString("CREATE INDEX ")
+		}
+		fmt.Fprintf(&str, "%s ON %s (%s);\n", postgresQuoteName(ddlIndexName(t, i)), postgresQuoteName(t.name), postgresQuoteNames(i.column))
+	}
+	if t.comment != "" {
+		fmt.Fprintf(&str, "COMMENT ON TABLE %s IS %s;\n", postgresQuoteName(t.name), postgresQuoteString(t.comment))
+	}
+	for _, c := range t.column {
+		if c.comment != "" {
+			fmt.Fprintf(&str, "COMMENT ON COLUMN %s.%s IS %s;\n", postgresQuoteName(t.name), postgresQuoteName(c.name), postgresQuoteString(c.comment))
+		}
+	}
+	return str.String()
+}
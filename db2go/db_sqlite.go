@@ -0,0 +1,278 @@
+package db2go
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqlite只用于作为目标数据库，比如生成DDL和复制数据，不能读取结构
+func init() {
+	dsnFunc[SQLITE] = sqliteParseDSN
+	ddlFunc[SQLITE] = sqliteTableDDL
+	quoteNameFunc[SQLITE] = postgresQuoteName
+	quoteStringFunc[SQLITE] = sqliteQuoteString
+	driver[SQLITE] = "github.com/mattn/go-sqlite3"
+}
+
+func sqliteQuoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// 文件路径，比如"data/test.db?_fk=1"，数据库名称是文件名
+func sqliteParseDSN(dsn string) (*DSN, error) {
+	d := new(DSN)
+	d.dbType = SQLITE
+	d.dsn = dsn
+	d.redacted = RedactDSN(dsn)
+	file := strings.TrimPrefix(dsn, "file:")
+	if i := strings.IndexByte(file, '?'); i >= 0 {
+		file = file[:i]
+	}
+	d.name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	return d, nil
+}
+
+// 列在sqlite中的类型，只有INTEGER，REAL，NUMERIC，TEXT和BLOB
+func sqliteColumnType(c *Column) string {
+	if c.dbType == SQLITE {
+		return c._type
+	}
+	switch c.DataType() {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "year":
+		return "INTEGER"
+	case "float", "double", "real":
+		return "REAL"
+	case "decimal", "numeric":
+		return "NUMERIC"
+	case "tinyblob", "blob", "mediumblob", "longblob", "binary", "varbinary", "bit":
+		return "BLOB"
+	default:
+		return "TEXT"
+	}
+}
+
+// sqlite的CREATE TABLE，自增的主键使用INTEGER PRIMARY KEY AUTOINCREMENT，索引是单独的语句
+func sqliteTableDDL(t *Table) string {
+	pk := t.primaryKey()
+	autoIncrement := len(pk) == 1 && pk[0].autoIncrement
+	var lines []string
+	for _, c := range t.column {
+		var str strings.Builder
+		str.WriteString("  ")
+		str.WriteString(postgresQuoteName(c.name))
+		str.WriteByte(' ')
+		str.WriteString(sqliteColumnType(c))
+		if autoIncrement && c == pk[0] {
+			str.WriteString(" PRIMARY KEY AUTOINCREMENT")
+			lines = append(lines, str.String())
+			continue
+		}
+		if !c.nullable {
+			str.WriteString(" NOT NULL")
+		}
+		if c.defaultValue != "" {
+			str.WriteString(" DEFAULT ")
+			str.WriteString(ddlDefaultValue(c, sqliteQuoteString))
+		}
+		lines = append(lines, str.String())
+	}
+	if len(pk) > 0 && !autoIncrement {
+		lines = append(lines, "  PRIMARY KEY ("+postgresQuoteNames(pk)+")")
+	}
+	for _, c := range t.column {
+		if c.foreignTable == nil || c.foreignTable.table == nil || c.foreignTable.column == nil {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  FOREIGN KEY (%s) REFERENCES %s (%s)", postgresQuoteName(c.name),
+			postgresQuoteName(c.foreignTable.table.name), postgresQuoteName(c.foreignTable.column.name)))
+	}
+	var str strings.Builder
+	str.WriteString("CREATE TABLE ")
+	str.WriteString(postgresQuoteName(t.name))
+	str.WriteString(" (\n")
+	str.WriteString(strings.Join(lines, ",\n"))
+	str.WriteString("\n);\n")
+	for _, i := range t.secondaryIndexes() {
+		if strings.EqualFold(i._type, "fulltext") {
+			continue
+		}
+		if i.unique {
+			str.WriteString("CREATE UNIQUE INDEX ")
+		} else {
+			str.WriteString("CREATE INDEX ")
+		}
+		fmt.Fprintf(&str, "%s ON %s (%s);\n", postgresQuoteName(ddlIndexName(t, i)), postgresQuoteName(t.name), postgresQuoteNames(i.column))
+	}
+	return str.String()
+}
+
+// sqlite是弱类型，列中的值可以和声明的类型不同，比如INTEGER列中的'1'和1.5，
+// 用于sql2go生成的代码，包装Scan的参数，按参数的类型转换，数字转成整数时去掉小数，
+// dest是int64，float64，bool，string，[]byte和对应的sql.NullXxx的指针
+func SQLiteScan(dest interface{}) sql.Scanner {
+	return &sqliteScanner{dest: dest}
+}
+
+type sqliteScanner struct {
+	dest interface{}
+}
+
+func (s *sqliteScanner) Scan(src interface{}) error {
+	var err error
+	switch d := s.dest.(type) {
+	case *int64:
+		*d, err = sqliteInt64(src)
+	case *sql.NullInt64:
+		*d = sql.NullInt64{}
+		if src != nil {
+			d.Int64, err = sqliteInt64(src)
+			d.Valid = err == nil
+		}
+	case *float64:
+		*d, err = sqliteFloat64(src)
+	case *sql.NullFloat64:
+		*d = sql.NullFloat64{}
+		if src != nil {
+			d.Float64, err = sqliteFloat64(src)
+			d.Valid = err == nil
+		}
+	case *bool:
+		*d, err = sqliteBool(src)
+	case *sql.NullBool:
+		*d = sql.NullBool{}
+		if src != nil {
+			d.Bool, err = sqliteBool(src)
+			d.Valid = err == nil
+		}
+	case *string:
+		*d, err = sqliteString(src)
+	case *sql.NullString:
+		*d = sql.NullString{}
+		if src != nil {
+			d.String, err = sqliteString(src)
+			d.Valid = err == nil
+		}
+	case *[]byte:
+		if src == nil {
+			*d = nil
+			break
+		}
+		var str string
+		str, err = sqliteString(src)
+		*d = []byte(str)
+	default:
+		return fmt.Errorf("sqlite scan: unsupported type %T", s.dest)
+	}
+	return err
+}
+
+// 不能转换，非sql.NullXxx的参数不能是NULL
+func sqliteConvertError(src interface{}, typ string) error {
+	if src == nil {
+		return fmt.Errorf("sqlite scan: converting NULL to %s is unsupported", typ)
+	}
+	return fmt.Errorf("sqlite scan: converting %T %v to %s is unsupported", src, src, typ)
+}
+
+func sqliteInt64(src interface{}) (int64, error) {
+	switch v := src.(type) {
+	case int64:
+		return v, nil
+	case float64:
+		return int64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case []byte:
+		return sqliteParseInt64(string(v))
+	case string:
+		return sqliteParseInt64(v)
+	}
+	return 0, sqliteConvertError(src, "int64")
+}
+
+func sqliteParseInt64(s string) (int64, error) {
+	if n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, sqliteConvertError(s, "int64")
+	}
+	return int64(f), nil
+}
+
+func sqliteFloat64(src interface{}) (float64, error) {
+	switch v := src.(type) {
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case []byte:
+		return sqliteParseFloat64(string(v))
+	case string:
+		return sqliteParseFloat64(v)
+	}
+	return 0, sqliteConvertError(src, "float64")
+}
+
+func sqliteParseFloat64(s string) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, sqliteConvertError(s, "float64")
+	}
+	return f, nil
+}
+
+// 数字不为0是true，字符串可以是true，false等
+func sqliteBool(src interface{}) (bool, error) {
+	switch v := src.(type) {
+	case bool:
+		return v, nil
+	case int64:
+		return v != 0, nil
+	case float64:
+		return v != 0, nil
+	case []byte, string:
+		str, _ := sqliteString(v)
+		if b, err := strconv.ParseBool(strings.TrimSpace(str)); err == nil {
+			return b, nil
+		}
+		f, err := sqliteParseFloat64(str)
+		if err != nil {
+			return false, sqliteConvertError(src, "bool")
+		}
+		return f != 0, nil
+	}
+	return false, sqliteConvertError(src, "bool")
+}
+
+// 时间使用go-sqlite3写入的格式
+func sqliteString(src interface{}) (string, error) {
+	switch v := src.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999999-07:00"), nil
+	}
+	return "", sqliteConvertError(src, "string")
+}
@@ -0,0 +1,67 @@
+package db2go
+
+import (
+	"database/sql"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+	"testing"
+)
+
+func TestSQLiteScan(t *testing.T) {
+	db, err := sql.Open(SQLITE, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	// 值和声明的类型不同
+	_, err = db.Exec(`create table t(i integer, f real, b boolean, s text, d blob);
+insert into t values('12', 1, 'true', 12, 'x');
+insert into t values(1.5, '2.5', 0, 1.5, 3);
+insert into t values(null, null, null, null, null);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query("select i,f,b,s,d from t where i is not null order by rowid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result []string
+	for rows.Next() {
+		var i int64
+		var f float64
+		var b bool
+		var s string
+		var d []byte
+		err = rows.Scan(SQLiteScan(&i), SQLiteScan(&f), SQLiteScan(&b), SQLiteScan(&s), SQLiteScan(&d))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, fmt.Sprintf("%v %v %v %v %s", i, f, b, s, d))
+	}
+	_ = rows.Close()
+	if len(result) != 2 || result[0] != "12 1 true 12 x" || result[1] != "1 2.5 false 1.5 3" {
+		t.Fatal(result)
+	}
+	var i sql.NullInt64
+	var f sql.NullFloat64
+	var b sql.NullBool
+	var s sql.NullString
+	var d []byte
+	err = db.QueryRow("select i,f,b,s,d from t where i is null").
+		Scan(SQLiteScan(&i), SQLiteScan(&f), SQLiteScan(&b), SQLiteScan(&s), SQLiteScan(&d))
+	if err != nil || i.Valid || f.Valid || b.Valid || s.Valid || d != nil {
+		t.Fatal(err)
+	}
+	// 不能转换
+	var n int64
+	err = db.QueryRow("select 'abc'").Scan(SQLiteScan(&n))
+	if err == nil {
+		t.FailNow()
+	}
+	err = db.QueryRow("select null").Scan(SQLiteScan(&n))
+	if err == nil {
+		t.FailNow()
+	}
+}
@@ -1,17 +1,53 @@
 package db2go
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestReadSchema(t *testing.T) {
 	s, err := ReadSchema(MYSQL, "root:123456@tcp(192.168.1.66)/db2go_test")
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer func() {
+		_ = s.Close()
+	}()
 	testT0(t, s, s.GetTable("t0"))
 	testT1(t, s, s.GetTable("t1"))
 	testT2(t, s, s.GetTable("t2"))
 	testT3(t, s, s.GetTable("t3"))
 	testT4(t, s, s.GetTable("t4"))
+	for _, table := range s.Tables() {
+		if table.Stats() == nil {
+			t.FailNow()
+		}
+	}
+	if s.GetTable("t1").Stats().AutoIncrement() < 1 {
+		t.FailNow()
+	}
+	if i := s.GetTable("t1").GetIndex("t1_name_uindex"); i == nil || !i.IsUnique() || len(i.Columns()) != 1 {
+		t.FailNow()
+	}
+}
+
+func TestMysqlNullTime(t *testing.T) {
+	var nt mysqlNullTime
+	err := nt.Scan([]byte("2020-01-02 03:04:05"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !nt.Valid || !nt.Time.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)) {
+		t.FailNow()
+	}
+	err = nt.Scan(nil)
+	if err != nil || nt.Valid {
+		t.FailNow()
+	}
+	err = nt.Scan("0000-00-00 00:00:00")
+	if err != nil || nt.Valid {
+		t.FailNow()
+	}
 }
 
 func testT0(t *testing.T, s *Schema, table *Table) {
@@ -135,3 +171,89 @@ func (tc *testColumn) test(t *testing.T, s *Schema, c *Column, typ, goType, def
 		}
 	}
 }
+
+// 不需要连接数据库的测试结构，与db_test.sql的t1，t2，t3相似
+func testSchema() *Schema {
+	s := &Schema{dbType: MYSQL, name: "db2go_test"}
+	t1 := &Table{name: "t1", comment: "t1"}
+	t1.column = []*Column{
+		{dbType: MYSQL, name: "id", _type: "int", primaryKey: true, autoIncrement: true, comment: "id"},
+		{dbType: MYSQL, name: "name", _type: "varchar(32)", unique: true, comment: "name"},
+	}
+	t1.index = []*Index{
+		{name: "PRIMARY", unique: true, primary: true, column: t1.column[:1]},
+		{name: "t1_name_uindex", unique: true, column: t1.column[1:]},
+	}
+	t2 := &Table{name: "t2"}
+	t2.column = []*Column{
+		{dbType: MYSQL, name: "id", _type: "int", primaryKey: true, autoIncrement: true},
+		{dbType: MYSQL, name: "name", _type: "varchar(32)", nullable: true},
+		{dbType: MYSQL, name: "price", _type: "double", nullable: true},
+	}
+	t2.index = []*Index{
+		{name: "PRIMARY", unique: true, primary: true, column: t2.column[:1]},
+	}
+	t3 := &Table{name: "t3"}
+	t3.column = []*Column{
+		{dbType: MYSQL, name: "id", _type: "int", primaryKey: true, autoIncrement: true},
+		{dbType: MYSQL, name: "t1_id", _type: "int", mulUnique: true, nullable: true},
+		{dbType: MYSQL, name: "t2", _type: "int", mulUnique: true, nullable: true},
+	}
+	t3.column[1].foreignTable = &ForeignTable{table: t1, column: t1.column[0]}
+	t3.column[2].foreignTable = &ForeignTable{table: t2, column: t2.column[0]}
+	t3.index = []*Index{
+		{name: "PRIMARY", unique: true, primary: true, column: t3.column[:1]},
+		{name: "t3_t1_id_t2_uindex", unique: true, column: t3.column[1:]},
+	}
+	t4 := &Table{name: "T4"}
+	t4.column = []*Column{
+		{dbType: MYSQL, name: "c1", _type: "int"},
+	}
+	s.table = []*Table{t1, t2, t3, t4}
+	for _, t := range s.table {
+		t.stats = new(TableStats)
+	}
+	return s
+}
+
+func TestColumnType(t *testing.T) {
+	c := &Column{_type: "decimal(10,5) unsigned"}
+	if c.DataType() != "decimal" || !c.IsUnsigned() || c.Length() != 10 || len(c.TypeArgs()) != 2 || c.TypeArgs()[1] != "5" {
+		t.FailNow()
+	}
+	c = &Column{_type: "enum('a','b,c','it''s')"}
+	args := c.TypeArgs()
+	if c.DataType() != "enum" || len(args) != 3 || args[1] != "b,c" || args[2] != "it's" {
+		t.Fatal(args)
+	}
+	c = &Column{_type: "int unsigned"}
+	if c.DataType() != "int" || c.TypeArgs() != nil || c.Length() != 0 {
+		t.FailNow()
+	}
+}
+
+func TestColumnGoType(t *testing.T) {
+	for _, c := range []struct {
+		typ      string
+		nullable bool
+		before   string // 以前的结果
+		want     string
+	}{
+		// column_type带参数，unsigned，zerofill
+		{"int(11) unsigned", false, "string", "uint"},
+		{"bigint(20) unsigned zerofill", false, "string", "uint64"},
+		{"tinyint(4)", true, "sql.NullString", "sql.NullInt32"},
+		{"varbinary(16)", false, "string", "[]byte"},
+		// 可以为NULL的[]byte，nil表示NULL
+		{"blob", true, "sql.NullString", "[]byte"},
+		// 没有变化
+		{"int", true, "sql.NullInt64", "sql.NullInt64"},
+		{"decimal(10,2)", false, "float64", "float64"},
+		{"varchar(32)", true, "sql.NullString", "sql.NullString"},
+	} {
+		col := &Column{dbType: MYSQL, _type: c.typ, nullable: c.nullable}
+		if typ := col.GoType(); typ != c.want {
+			t.Errorf("%s: got %s, want %s (before %s)", c.typ, typ, c.want, c.before)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package db2go
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+)
+
+var (
+	ddlFunc = make(map[string]func(*Table) string)
+	// 不需要加引号的默认值
+	ddlDefaultExprRegexp = regexp.MustCompile(`(?i)^(current_timestamp|now|localtime|localtimestamp|current_date|current_time)(\(\d*\))?$|^\(.*\)$`)
+)
+
+// 表的CREATE TABLE语句，dbType是目标数据库
+func TableDDL(dbType string, t *Table) (string, error) {
+	f, o := ddlFunc[dbType]
+	if !o {
+		return "", fmt.Errorf("unsupported db '%s'", dbType)
+	}
+	return f(t), nil
+}
+
+// 输出所有表的CREATE TABLE语句，按外键依赖排序
+func WriteDDL(w io.Writer, s *Schema, dbType string) error {
+	f, o := ddlFunc[dbType]
+	if !o {
+		return fmt.Errorf("unsupported db '%s'", dbType)
+	}
+	for i, t := range s.SortedTables() {
+		if i > 0 {
+			_, err := io.WriteString(w, "\n")
+			if err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(w, f(t))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 主键列，优先使用索引信息
+func (t *Table) primaryKey() []*Column {
+	for _, i := range t.index {
+		if i.primary {
+			return i.column
+		}
+	}
+	pk, _ := t.PrimaryKeyColumns()
+	return pk
+}
+
+// 非主键的索引，没有索引信息时使用列的unique
+func (t *Table) secondaryIndexes() []*Index {
+	var indexes []*Index
+	if len(t.index) > 0 {
+		for _, i := range t.index {
+			if !i.primary {
+				indexes = append(indexes, i)
+			}
+		}
+		return indexes
+	}
+	for _, c := range t.column {
+		if c.unique && !c.primaryKey {
+			indexes = append(indexes, &Index{name: t.name + "_" + c.name + "_uindex", unique: true, column: []*Column{c}})
+		}
+	}
+	return indexes
+}
+
+// 默认值，字符串使用quote加引号
+func ddlDefaultValue(c *Column, quote func(string) string) string {
+	if ddlDefaultExprRegexp.MatchString(c.defaultValue) {
+		return c.defaultValue
+	}
+	switch c.GoType() {
+	case "int8", "int16", "int32", "int", "int64", "uint8", "uint16", "uint32", "uint", "uint64", "float32", "float64",
+		"sql.NullInt32", "sql.NullInt64", "sql.NullFloat64":
+		return c.defaultValue
+	}
+	return quote(c.defaultValue)
+}
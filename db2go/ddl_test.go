@@ -0,0 +1,99 @@
+package db2go
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSortedTables(t *testing.T) {
+	s := testSchema()
+	// t3引用t1和t2，放到最前面
+	s.table = append([]*Table{s.table[2]}, s.table[0], s.table[1], s.table[3])
+	var names []string
+	for _, t := range s.SortedTables() {
+		names = append(names, t.name)
+	}
+	if strings.Join(names, ",") != "t1,t2,T4,t3" {
+		t.Fatal(names)
+	}
+}
+
+func TestTableDDL(t *testing.T) {
+	s := testSchema()
+	s.GetTable("t2").GetColumn("name").defaultValue = "it's"
+	ddl, err := TableDDL(MYSQL, s.GetTable("t2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ddl != "CREATE TABLE `t2` (\n"+
+		"  `id` int NOT NULL AUTO_INCREMENT,\n"+
+		"  `name` varchar(32) NULL DEFAULT 'it''s',\n"+
+		"  `price` double NULL,\n"+
+		"  PRIMARY KEY (`id`)\n"+
+		");\n" {
+		t.Fatal(ddl)
+	}
+	ddl, err = TableDDL(MYSQL, s.GetTable("t3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(ddl, "UNIQUE KEY `t3_t1_id_t2_uindex` (`t1_id`,`t2`)") ||
+		!strings.Contains(ddl, "CONSTRAINT `t3_t1_id_fk` FOREIGN KEY (`t1_id`) REFERENCES `t1` (`id`)") {
+		t.Fatal(ddl)
+	}
+	_, err = TableDDL("unknown", s.GetTable("t3"))
+	if err == nil {
+		t.FailNow()
+	}
+}
+
+func TestTableDDLDialect(t *testing.T) {
+	s := testSchema()
+	s.GetTable("t2").comment = "it's t2"
+	ddl, err := TableDDL(POSTGRES, s.GetTable("t2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ddl != "CREATE TABLE \"t2\" (\n"+
+		"  \"id\" serial NOT NULL,\n"+
+		"  \"name\" varchar(32) NULL,\n"+
+		"  \"price\" double precision NULL,\n"+
+		"  PRIMARY KEY (\"id\")\n"+
+		");\n"+
+		"COMMENT ON TABLE \"t2\" IS 'it''s t2';\n" {
+		t.Fatal(ddl)
+	}
+	ddl, err = TableDDL(SQLITE, s.GetTable("t3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(ddl, "  \"id\" INTEGER PRIMARY KEY AUTOINCREMENT,\n") ||
+		!strings.Contains(ddl, "CREATE UNIQUE INDEX \"t3_t1_id_t2_uindex\" ON \"t3\" (\"t1_id\",\"t2\");") ||
+		!strings.Contains(ddl, "FOREIGN KEY (\"t1_id\") REFERENCES \"t1\" (\"id\")") {
+		t.Fatal(ddl)
+	}
+	if len(SplitStatements(ddl)) != 2 {
+		t.Fatal(ddl)
+	}
+}
+
+func TestTableDDLSchemaDBType(t *testing.T) {
+	for _, dbType := range []string{POSTGRES, SQLITE} {
+		s, err := ReadSchemaJSON(strings.NewReader(`{"dbType": "` + dbType + `", "name": "db2go_test", "tables": [
+  {"name": "t1", "columns": [
+    {"name": "id", "type": "integer", "primaryKey": true},
+    {"name": "name", "type": "text", "default": "it's"}
+  ]}
+]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ddl, err := TableDDL(dbType, s.GetTable("t1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(ddl, "DEFAULT 'it''s'") {
+			t.Fatal(ddl)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package db2go
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	TableAdded    = "table-added"
+	TableRemoved  = "table-removed"
+	TableChanged  = "table-changed"
+	ColumnAdded   = "column-added"
+	ColumnRemoved = "column-removed"
+	ColumnChanged = "column-changed"
+	IndexAdded    = "index-added"
+	IndexRemoved  = "index-removed"
+	IndexChanged  = "index-changed"
+)
+
+// 两个结构的一处不同
+type SchemaChange struct {
+	Type   string `json:"type"`             // 变化的类型
+	Table  string `json:"table"`            // 表名
+	Column string `json:"column,omitempty"` // 列名
+	Index  string `json:"index,omitempty"`  // 索引名
+	From   string `json:"from,omitempty"`   // 原来的定义
+	To     string `json:"to,omitempty"`     // 现在的定义
+}
+
+func (c *SchemaChange) String() string {
+	var str strings.Builder
+	str.WriteString(c.Type)
+	str.WriteByte(' ')
+	str.WriteString(c.Table)
+	if c.Column != "" {
+		str.WriteByte('.')
+		str.WriteString(c.Column)
+	}
+	if c.Index != "" {
+		str.WriteString(" index ")
+		str.WriteString(c.Index)
+	}
+	switch {
+	case c.From != "" && c.To != "":
+		fmt.Fprintf(&str, ": %s -> %s", c.From, c.To)
+	case c.From != "":
+		fmt.Fprintf(&str, ": %s", c.From)
+	case c.To != "":
+		fmt.Fprintf(&str, ": %s", c.To)
+	}
+	return str.String()
+}
+
+// 比较两个结构，返回from变成to的所有变化，不比较统计信息
+func DiffSchema(from, to *Schema) []*SchemaChange {
+	var changes []*SchemaChange
+	for _, ft := range from.table {
+		if to.GetTable(ft.name) == nil {
+			changes = append(changes, &SchemaChange{Type: TableRemoved, Table: ft.name})
+		}
+	}
+	for _, tt := range to.table {
+		ft := from.GetTable(tt.name)
+		if ft == nil {
+			changes = append(changes, &SchemaChange{Type: TableAdded, Table: tt.name})
+			continue
+		}
+		changes = append(changes, DiffTable(ft, tt)...)
+	}
+	return changes
+}
+
+// 比较两个表
+func DiffTable(from, to *Table) []*SchemaChange {
+	var changes []*SchemaChange
+	if from.comment != to.comment {
+		changes = append(changes, &SchemaChange{Type: TableChanged, Table: to.name, From: "comment " + from.comment, To: "comment " + to.comment})
+	}
+	// 列
+	for _, fc := range from.column {
+		if to.GetColumn(fc.name) == nil {
+			changes = append(changes, &SchemaChange{Type: ColumnRemoved, Table: to.name, Column: fc.name, From: fc.Definition()})
+		}
+	}
+	for _, tc := range to.column {
+		fc := from.GetColumn(tc.name)
+		if fc == nil {
+			changes = append(changes, &SchemaChange{Type: ColumnAdded, Table: to.name, Column: tc.name, To: tc.Definition()})
+			continue
+		}
+		if f, t := fc.Definition(), tc.Definition(); f != t {
+			changes = append(changes, &SchemaChange{Type: ColumnChanged, Table: to.name, Column: tc.name, From: f, To: t})
+		}
+	}
+	// 索引
+	for _, fi := range from.index {
+		if to.GetIndex(fi.name) == nil {
+			changes = append(changes, &SchemaChange{Type: IndexRemoved, Table: to.name, Index: fi.name, From: fi.Definition()})
+		}
+	}
+	for _, ti := range to.index {
+		fi := from.GetIndex(ti.name)
+		if fi == nil {
+			changes = append(changes, &SchemaChange{Type: IndexAdded, Table: to.name, Index: ti.name, To: ti.Definition()})
+			continue
+		}
+		if f, t := fi.Definition(), ti.Definition(); f != t {
+			changes = append(changes, &SchemaChange{Type: IndexChanged, Table: to.name, Index: ti.name, From: f, To: t})
+		}
+	}
+	return changes
+}
+
+// 列的定义，用于比较和显示，比如"int NOT NULL AUTO_INCREMENT PRIMARY KEY"
+func (c *Column) Definition() string {
+	var str strings.Builder
+	str.WriteString(c._type)
+	if c.nullable {
+		str.WriteString(" NULL")
+	} else {
+		str.WriteString(" NOT NULL")
+	}
+	if c.defaultValue != "" {
+		str.WriteString(" DEFAULT ")
+		str.WriteString(c.defaultValue)
+	}
+	if c.autoIncrement {
+		str.WriteString(" AUTO_INCREMENT")
+	}
+	if c.primaryKey {
+		str.WriteString(" PRIMARY KEY")
+	}
+	if c.unique {
+		str.WriteString(" UNIQUE")
+	}
+	if c.foreignTable != nil && c.foreignTable.table != nil && c.foreignTable.column != nil {
+		str.WriteString(" REFERENCES ")
+		str.WriteString(c.foreignTable.table.name)
+		str.WriteByte('(')
+		str.WriteString(c.foreignTable.column.name)
+		str.WriteByte(')')
+	}
+	if c.comment != "" {
+		str.WriteString(" COMMENT ")
+		str.WriteString(c.comment)
+	}
+	return str.String()
+}
+
+// 索引的定义，用于比较和显示，比如"UNIQUE BTREE (a,b)"
+func (i *Index) Definition() string {
+	var str strings.Builder
+	if i.primary {
+		str.WriteString("PRIMARY ")
+	} else if i.unique {
+		str.WriteString("UNIQUE ")
+	}
+	if i._type != "" {
+		str.WriteString(i._type)
+		str.WriteByte(' ')
+	}
+	str.WriteByte('(')
+	for k, c := range i.column {
+		if k > 0 {
+			str.WriteByte(',')
+		}
+		str.WriteString(c.name)
+	}
+	str.WriteByte(')')
+	return str.String()
+}
@@ -0,0 +1,73 @@
+package db2go
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffSchema(t *testing.T) {
+	from := testSchema()
+	to := testSchema()
+	to.table = to.table[:3]
+	t1 := to.GetTable("t1")
+	t1.column[1]._type = "varchar(64)"
+	t1.column = append(t1.column, &Column{dbType: MYSQL, name: "age", _type: "int", nullable: true})
+	t1.index = t1.index[:1]
+	t3 := to.GetTable("t3")
+	t3.column = t3.column[:2]
+	t3.index[1].column = t3.column[1:2]
+	changes := DiffSchema(from, to)
+	expect := []string{
+		"table-removed T4",
+		"column-changed t1.name: varchar(32) NOT NULL UNIQUE COMMENT name -> varchar(64) NOT NULL UNIQUE COMMENT name",
+		"column-added t1.age: int NULL",
+		"index-removed t1 index t1_name_uindex: UNIQUE (name)",
+		"column-removed t3.t2: int NULL REFERENCES t2(id)",
+		"index-changed t3 index t3_t1_id_t2_uindex: UNIQUE (t1_id,t2) -> UNIQUE (t1_id)",
+	}
+	if len(changes) != len(expect) {
+		t.Fatal(changes)
+	}
+	for i, c := range changes {
+		if c.String() != expect[i] {
+			t.Fatal(c.String())
+		}
+	}
+}
+
+func TestDiffSchemaStruct(t *testing.T) {
+	from, err := ReadSchemaGo("model.go", testStructSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// mysql读取的结构，索引的类型是BTREE
+	to, err := ReadSchemaJSON(strings.NewReader(`{"dbType": "mysql", "name": "model", "tables": [
+  {"name": "user", "columns": [
+    {"name": "id", "type": "bigint", "primaryKey": true, "autoIncrement": true},
+    {"name": "created_at", "type": "datetime"},
+    {"name": "name", "type": "varchar(32)", "unique": true},
+    {"name": "email", "type": "varchar(255)", "nullable": true},
+    {"name": "salt", "type": "varbinary(16)", "nullable": true}
+  ], "indexes": [
+    {"name": "PRIMARY", "type": "BTREE", "unique": true, "primary": true, "columns": ["id"]},
+    {"name": "user_name_uindex", "type": "BTREE", "unique": true, "columns": ["name"]}
+  ]},
+  {"name": "orders", "columns": [
+    {"name": "id", "type": "bigint", "primaryKey": true, "autoIncrement": true},
+    {"name": "user_id", "type": "bigint", "foreignKey": {"table": "user", "column": "id"}},
+    {"name": "price", "type": "decimal(10,2)", "default": "0"},
+    {"name": "count", "type": "bigint"},
+    {"name": "flags", "type": "bigint unsigned"}
+  ], "indexes": [
+    {"name": "PRIMARY", "type": "BTREE", "unique": true, "primary": true, "columns": ["id"]},
+    {"name": "orders_user_id_index", "type": "BTREE", "columns": ["user_id"]}
+  ]}
+]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := DiffSchema(from, to)
+	if len(changes) != 0 {
+		t.Fatal(changes)
+	}
+}
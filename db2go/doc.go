@@ -0,0 +1,164 @@
+package db2go
+
+import (
+	htmlTemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+)
+
+var _docMarkdownTPL = template.Must(template.New("docMarkdownTPL").Funcs(template.FuncMap{
+	"cell":   docMarkdownCell,
+	"anchor": strings.ToLower,
+	"yes":    docYes,
+}).Parse(`# {{.Name}}
+
+| 表 | 注释 | 估算行数 |
+| --- | --- | --- |
+{{- range .Tables}}
+| [{{.Name}}](#{{anchor .Name}}) | {{cell .Comment}} | {{with .Stats}}{{.Rows}}{{end}} |
+{{- end}}
+{{range .Tables}}
+## {{.Name}}
+{{- if .Comment}}
+
+{{.Comment}}
+{{- end}}
+
+| 列 | 类型 | 可空 | 默认值 | 键 | 引用 | 注释 |
+| --- | --- | --- | --- | --- | --- | --- |
+{{- range .Columns}}
+| {{.Name}} | {{cell .Type}} | {{yes .IsNullable}} | {{cell .DefaultValue}} | {{$.Keys .}} | {{with .ForeignTable}}[{{.Table.Name}}.{{.Column.Name}}](#{{anchor .Table.Name}}){{end}} | {{cell .Comment}} |
+{{- end}}
+{{- if .Indexes}}
+
+| 索引 | 类型 | 唯一 | 列 |
+| --- | --- | --- | --- |
+{{- range .Indexes}}
+| {{.Name}} | {{.Type}} | {{yes .IsUnique}} | {{$.IndexColumns .}} |
+{{- end}}
+{{- end}}
+{{- with $.ReferencedBy .}}
+
+被引用：{{range $i, $r := .}}{{if $i}}，{{end}}[{{$r.Table.Name}}.{{$r.Column.Name}}](#{{anchor $r.Table.Name}}){{end}}
+{{- end}}
+{{end}}`))
+
+var _docHTMLTPL = htmlTemplate.Must(htmlTemplate.New("docHTMLTPL").Funcs(htmlTemplate.FuncMap{
+	"yes": docYes,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: -apple-system, "Helvetica Neue", Arial, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+h2 { margin-top: 2em; border-bottom: 1px solid #ddd; }
+.comment { color: #666; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<table>
+<tr><th>表</th><th>注释</th><th>估算行数</th></tr>
+{{- range .Tables}}
+<tr><td><a href="#{{.Name}}">{{.Name}}</a></td><td>{{.Comment}}</td><td>{{with .Stats}}{{.Rows}}{{end}}</td></tr>
+{{- end}}
+</table>
+{{- range .Tables}}
+<h2 id="{{.Name}}">{{.Name}}</h2>
+{{- if .Comment}}
+<p class="comment">{{.Comment}}</p>
+{{- end}}
+<table>
+<tr><th>列</th><th>类型</th><th>可空</th><th>默认值</th><th>键</th><th>引用</th><th>注释</th></tr>
+{{- range .Columns}}
+<tr><td>{{.Name}}</td><td>{{.Type}}</td><td>{{yes .IsNullable}}</td><td>{{.DefaultValue}}</td><td>{{$.Keys .}}</td><td>{{with .ForeignTable}}<a href="#{{.Table.Name}}">{{.Table.Name}}.{{.Column.Name}}</a>{{end}}</td><td>{{.Comment}}</td></tr>
+{{- end}}
+</table>
+{{- if .Indexes}}
+<table>
+<tr><th>索引</th><th>类型</th><th>唯一</th><th>列</th></tr>
+{{- range .Indexes}}
+<tr><td>{{.Name}}</td><td>{{.Type}}</td><td>{{yes .IsUnique}}</td><td>{{$.IndexColumns .}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- with $.ReferencedBy .}}
+<p>被引用：{{range $i, $r := .}}{{if $i}}，{{end}}<a href="#{{$r.Table.Name}}">{{$r.Table.Name}}.{{$r.Column.Name}}</a>{{end}}</p>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// 数据字典模板的数据
+type docTPL struct {
+	*Schema
+}
+
+// 列的键，PK/UK/MUL/FK
+func (t *docTPL) Keys(c *Column) string {
+	var keys []string
+	if c.primaryKey {
+		keys = append(keys, "PK")
+	}
+	if c.unique {
+		keys = append(keys, "UK")
+	}
+	if c.mulUnique {
+		keys = append(keys, "MUL")
+	}
+	if c.foreignTable != nil {
+		keys = append(keys, "FK")
+	}
+	return strings.Join(keys, ",")
+}
+
+func (t *docTPL) IndexColumns(i *Index) string {
+	var names []string
+	for _, c := range i.column {
+		names = append(names, c.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// 引用了table的列
+func (t *docTPL) ReferencedBy(table *Table) []*ForeignTable {
+	var refs []*ForeignTable
+	for _, tb := range t.table {
+		for _, c := range tb.column {
+			if c.foreignTable != nil && c.foreignTable.table == table {
+				refs = append(refs, &ForeignTable{table: tb, column: c})
+			}
+		}
+	}
+	return refs
+}
+
+// 输出Markdown格式的数据字典
+func WriteMarkdownDoc(w io.Writer, s *Schema) error {
+	return _docMarkdownTPL.Execute(w, &docTPL{Schema: s})
+}
+
+// 输出自包含的HTML格式的数据字典
+func WriteHTMLDoc(w io.Writer, s *Schema) error {
+	return _docHTMLTPL.Execute(w, &docTPL{Schema: s})
+}
+
+// 表格里不能有|和换行
+func docMarkdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+func docYes(b bool) string {
+	if b {
+		return "YES"
+	}
+	return ""
+}
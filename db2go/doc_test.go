@@ -0,0 +1,33 @@
+package db2go
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteDoc(t *testing.T) {
+	s := testSchema()
+	var str strings.Builder
+	err := WriteMarkdownDoc(&str, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range []string{
+		"| [T4](#t4) |  | 0 |",
+		"| t1_id | int | YES |  | MUL,FK | [t1.id](#t1) |  |",
+		"被引用：[t3.t1_id](#t3)",
+		"| t3_t1_id_t2_uindex |  | YES | t1_id, t2 |",
+	} {
+		if !strings.Contains(str.String(), sub) {
+			t.Fatal(sub)
+		}
+	}
+	str.Reset()
+	err = WriteHTMLDoc(&str, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(str.String(), `<h2 id="t3">t3</h2>`) || !strings.Contains(str.String(), `<a href="#t1">t1.id</a>`) {
+		t.Fatal(str.String())
+	}
+}
@@ -0,0 +1,319 @@
+/*
+逻辑备份，按外键依赖的顺序导出表的数据，格式是批量的insert语句或者json lines，
+用Restore导入到另一个数据库。
+*/
+package dump
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"github.com/qq51529210/db/db2go/mask"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatSQL    = "sql"   // 批量的insert语句，每条一行
+	FormatJSON   = "jsonl" // 每行一个json对象，{"table":"user","row":{"id":1}}
+	DefaultBatch = 100     // 默认每条insert的行数
+)
+
+var (
+	errNoDB = errors.New("schema has no database connection")
+)
+
+// json lines的一行
+type jsonRow struct {
+	Table string                 `json:"table"`
+	Row   map[string]interface{} `json:"row"`
+}
+
+// 按GoType区分的值的类型
+const (
+	kindString = iota
+	kindNumber
+	kindBinary
+)
+
+func columnKind(c *db2go.Column) int {
+	switch c.GoType() {
+	case "int8", "int16", "int32", "int", "int64", "uint8", "uint16", "uint32", "uint", "uint64", "float32", "float64",
+		"sql.NullInt32", "sql.NullInt64", "sql.NullFloat64":
+		return kindNumber
+	case "[]byte":
+		return kindBinary
+	default:
+		return kindString
+	}
+}
+
+// 把驱动返回的值转换成nil，[]byte（二进制），json.Number（数字）或者string
+func normalize(c *db2go.Column, v interface{}) interface{} {
+	kind := columnKind(c)
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		if kind == kindBinary {
+			b := make([]byte, len(v))
+			copy(b, v)
+			return b
+		}
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		if v {
+			s = "1"
+		} else {
+			s = "0"
+		}
+	case time.Time:
+		s = v.Format("2006-01-02 15:04:05.999999")
+	default:
+		s = fmt.Sprint(v)
+	}
+	switch kind {
+	case kindNumber:
+		return json.Number(s)
+	case kindBinary:
+		return []byte(s)
+	default:
+		return s
+	}
+}
+
+// 值的sql常量
+func literal(dbType string, v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return "X'" + hex.EncodeToString(v) + "'"
+	case json.Number:
+		return string(v)
+	default:
+		return db2go.QuoteString(dbType, fmt.Sprint(v))
+	}
+}
+
+// 导出数据
+type Dumper struct {
+	schema *db2go.Schema
+	format string
+	batch  int
+	tables map[string]bool   // 导出的表，空表示全部
+	where  map[string]string // 表的过滤条件
+	masker *mask.Masker      // 脱敏
+}
+
+// 使用schema的连接池导出，默认格式是FormatSQL
+func NewDumper(schema *db2go.Schema) *Dumper {
+	d := new(Dumper)
+	d.schema = schema
+	d.format = FormatSQL
+	d.batch = DefaultBatch
+	d.tables = make(map[string]bool)
+	d.where = make(map[string]string)
+	return d
+}
+
+// FormatSQL或者FormatJSON
+func (d *Dumper) SetFormat(format string) error {
+	switch format {
+	case FormatSQL, FormatJSON:
+		d.format = format
+		return nil
+	default:
+		return fmt.Errorf("unsupported format '%s'", format)
+	}
+}
+
+// 每条insert语句的行数
+func (d *Dumper) SetBatch(rows int) {
+	if rows < 1 {
+		rows = 1
+	}
+	d.batch = rows
+}
+
+// 只导出这些表，被引用的表不会自动加入
+func (d *Dumper) SetTables(tables ...string) {
+	for _, t := range tables {
+		d.tables[t] = true
+	}
+}
+
+// 表的过滤条件，比如"created_at > '2020-01-01'"
+func (d *Dumper) SetWhere(table, where string) {
+	d.where[table] = where
+}
+
+// 导出时对数据脱敏
+func (d *Dumper) SetMasker(masker *mask.Masker) {
+	d.masker = masker
+}
+
+// 按外键依赖的顺序导出
+func (d *Dumper) Dump(w io.Writer) error {
+	if d.schema.DB() == nil {
+		return errNoDB
+	}
+	for name := range d.tables {
+		if d.schema.GetTable(name) == nil {
+			return fmt.Errorf("table '%s' not found", name)
+		}
+	}
+	for name := range d.where {
+		if d.schema.GetTable(name) == nil {
+			return fmt.Errorf("table '%s' not found", name)
+		}
+	}
+	for _, t := range d.schema.SortedTables() {
+		if len(d.tables) > 0 && !d.tables[t.Name()] {
+			continue
+		}
+		err := d.DumpTable(w, t)
+		if err != nil {
+			return fmt.Errorf("table '%s': %v", t.Name(), err)
+		}
+	}
+	return nil
+}
+
+// 导出一个表
+func (d *Dumper) DumpTable(w io.Writer, table *db2go.Table) error {
+	if d.schema.DB() == nil {
+		return errNoDB
+	}
+	dbType := d.schema.DBType()
+	columns := table.Columns()
+	rows, err := d.schema.DB().Query(d.selectSQL(table))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	var enc *json.Encoder
+	if d.format == FormatJSON {
+		enc = json.NewEncoder(w)
+	} else {
+		_, err = fmt.Fprintf(w, "-- table %s\n", table.Name())
+		if err != nil {
+			return err
+		}
+	}
+	values := make([]interface{}, len(columns))
+	scans := make([]interface{}, len(columns))
+	for i := range values {
+		scans[i] = &values[i]
+	}
+	var batch []string
+	for rows.Next() {
+		err = rows.Scan(scans...)
+		if err != nil {
+			return err
+		}
+		if enc != nil {
+			row := make(map[string]interface{})
+			for i, c := range columns {
+				row[c.Name()] = d.value(table, c, values[i])
+			}
+			err = enc.Encode(&jsonRow{Table: table.Name(), Row: row})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		var str strings.Builder
+		str.WriteByte('(')
+		for i, c := range columns {
+			if i > 0 {
+				str.WriteByte(',')
+			}
+			str.WriteString(literal(dbType, d.value(table, c, values[i])))
+		}
+		str.WriteByte(')')
+		batch = append(batch, str.String())
+		if len(batch) >= d.batch {
+			err = d.writeInsert(w, table, batch)
+			if err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return d.writeInsert(w, table, batch)
+	}
+	return nil
+}
+
+// 以json lines的格式输出表的一行，values和table.Columns()一一对应，可以用Restore导入
+func WriteJSONRow(w io.Writer, table *db2go.Table, values []interface{}) error {
+	row := make(map[string]interface{})
+	for i, c := range table.Columns() {
+		row[c.Name()] = normalize(c, values[i])
+	}
+	return json.NewEncoder(w).Encode(&jsonRow{Table: table.Name(), Row: row})
+}
+
+// 转换并脱敏
+func (d *Dumper) value(table *db2go.Table, c *db2go.Column, v interface{}) interface{} {
+	v = normalize(c, v)
+	if d.masker != nil {
+		v = d.masker.Mask(table, c, v)
+	}
+	return v
+}
+
+func (d *Dumper) selectSQL(table *db2go.Table) string {
+	dbType := d.schema.DBType()
+	var str strings.Builder
+	str.WriteString("select ")
+	str.WriteString(quoteColumns(dbType, table.Columns()))
+	str.WriteString(" from ")
+	str.WriteString(db2go.QuoteName(dbType, table.Name()))
+	if where := d.where[table.Name()]; where != "" {
+		str.WriteString(" where ")
+		str.WriteString(where)
+	}
+	// 按主键排序，每次导出的顺序一样
+	pk, _ := table.PrimaryKeyColumns()
+	if len(pk) > 0 {
+		str.WriteString(" order by ")
+		str.WriteString(quoteColumns(dbType, pk))
+	}
+	return str.String()
+}
+
+// 一条insert语句，一行
+func (d *Dumper) writeInsert(w io.Writer, table *db2go.Table, values []string) error {
+	dbType := d.schema.DBType()
+	_, err := fmt.Fprintf(w, "insert into %s(%s) values%s;\n",
+		db2go.QuoteName(dbType, table.Name()), quoteColumns(dbType, table.Columns()), strings.Join(values, ","))
+	return err
+}
+
+func quoteColumns(dbType string, columns []*db2go.Column) string {
+	var names []string
+	for _, c := range columns {
+		names = append(names, db2go.QuoteName(dbType, c.Name()))
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,62 @@
+package dump
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/qq51529210/db/db2go"
+	"strings"
+	"testing"
+)
+
+// 读取testdata/schema.json的结构
+func testSchema(t *testing.T) *db2go.Schema {
+	s, err := db2go.ReadSchemaJSONFile("testdata/schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestLiteral(t *testing.T) {
+	s := testSchema(t)
+	table := s.GetTable("t1")
+	values := []interface{}{[]byte("1"), []byte("it's\n"), []byte{0, 1}, []byte("1.50")}
+	var literals []string
+	for i, c := range table.Columns() {
+		literals = append(literals, literal(s.DBType(), normalize(c, values[i])))
+	}
+	if strings.Join(literals, ",") != `1,'it''s\n',X'0001',1.50` {
+		t.Fatal(literals)
+	}
+	if literal(s.DBType(), normalize(table.GetColumn("name"), nil)) != "NULL" {
+		t.FailNow()
+	}
+	// json
+	row := make(map[string]interface{})
+	for i, c := range table.Columns() {
+		row[c.Name()] = normalize(c, values[i])
+	}
+	data, err := json.Marshal(&jsonRow{Table: "t1", Row: row})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"table":"t1","row":{"data":"AAE=","id":1,"name":"it's\n","price":1.50}}` {
+		t.Fatal(string(data))
+	}
+	v, err := decodeValue(table.GetColumn("data"), "AAE=")
+	if err != nil || !bytes.Equal(v.([]byte), []byte{0, 1}) {
+		t.Fatal(v, err)
+	}
+}
+
+func TestSelectSQL(t *testing.T) {
+	s := testSchema(t)
+	d := NewDumper(s)
+	d.SetWhere("t1", "id > 10")
+	if q := d.selectSQL(s.GetTable("t1")); q != "select `id`,`name`,`data`,`price` from `t1` where id > 10 order by `id`" {
+		t.Fatal(q)
+	}
+	if d.SetFormat("csv") == nil {
+		t.FailNow()
+	}
+}
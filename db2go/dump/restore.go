@@ -0,0 +1,196 @@
+package dump
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"io"
+	"strings"
+)
+
+var (
+	// 关闭和打开外键检查
+	foreignKeyChecks = map[string][2]string{
+		db2go.MYSQL: {"set foreign_key_checks=0", "set foreign_key_checks=1"},
+	}
+)
+
+// 导入Dump导出的数据，schema是目标数据库的结构，导入期间关闭外键检查
+func Restore(schema *db2go.Schema, r io.Reader, format string) error {
+	if schema.DB() == nil {
+		return errNoDB
+	}
+	switch format {
+	case FormatSQL:
+		return withConn(schema.DB(), schema.DBType(), func(conn *sql.Conn) error {
+			return restoreSQL(conn, r)
+		})
+	case FormatJSON:
+		return withConn(schema.DB(), schema.DBType(), func(conn *sql.Conn) error {
+			return restoreJSON(conn, schema, r)
+		})
+	default:
+		return fmt.Errorf("unsupported format '%s'", format)
+	}
+}
+
+// 每行一条语句，--开头的是注释
+func restoreSQL(conn *sql.Conn, r io.Reader) error {
+	reader := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		s := strings.TrimSpace(line)
+		if s != "" && !strings.HasPrefix(s, "--") {
+			_, err2 := conn.ExecContext(context.Background(), strings.TrimSuffix(s, ";"))
+			if err2 != nil {
+				return fmt.Errorf("line %d: %v", n, err2)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// 同一个表并且列相同的连续的行
+type jsonBatch struct {
+	table   *db2go.Table
+	columns []*db2go.Column
+	rows    [][]interface{}
+}
+
+func restoreJSON(conn *sql.Conn, schema *db2go.Schema, r io.Reader) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	batch := new(jsonBatch)
+	for n := 1; ; n++ {
+		var row jsonRow
+		err := dec.Decode(&row)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("row %d: %v", n, err)
+		}
+		table := schema.GetTable(row.Table)
+		if table == nil {
+			return fmt.Errorf("row %d: table '%s' not found", n, row.Table)
+		}
+		var columns []*db2go.Column
+		for k := range row.Row {
+			if table.GetColumn(k) == nil {
+				return fmt.Errorf("row %d: unknown column '%s' in table '%s'", n, k, row.Table)
+			}
+		}
+		for _, c := range table.Columns() {
+			if _, ok := row.Row[c.Name()]; ok {
+				columns = append(columns, c)
+			}
+		}
+		if batch.table != table || !sameColumns(batch.columns, columns) || len(batch.rows) >= DefaultBatch {
+			err = batch.insert(conn, schema.DBType())
+			if err != nil {
+				return err
+			}
+			batch = &jsonBatch{table: table, columns: columns}
+		}
+		values := make([]interface{}, len(columns))
+		for i, c := range columns {
+			values[i], err = decodeValue(c, row.Row[c.Name()])
+			if err != nil {
+				return fmt.Errorf("row %d: column '%s': %v", n, c.Name(), err)
+			}
+		}
+		batch.rows = append(batch.rows, values)
+	}
+	return batch.insert(conn, schema.DBType())
+}
+
+func sameColumns(a, b []*db2go.Column) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// 二进制的列在json中是base64
+func decodeValue(c *db2go.Column, v interface{}) (interface{}, error) {
+	if s, ok := v.(string); ok && columnKind(c) == kindBinary {
+		return base64.StdEncoding.DecodeString(s)
+	}
+	if n, ok := v.(json.Number); ok {
+		return n.String(), nil
+	}
+	return v, nil
+}
+
+func (b *jsonBatch) insert(conn *sql.Conn, dbType string) error {
+	if len(b.rows) < 1 {
+		return nil
+	}
+	var str strings.Builder
+	var args []interface{}
+	str.WriteString("insert into ")
+	str.WriteString(db2go.QuoteName(dbType, b.table.Name()))
+	str.WriteByte('(')
+	str.WriteString(quoteColumns(dbType, b.columns))
+	str.WriteString(") values")
+	for i, row := range b.rows {
+		if i > 0 {
+			str.WriteByte(',')
+		}
+		str.WriteByte('(')
+		for j, v := range row {
+			if j > 0 {
+				str.WriteByte(',')
+			}
+			args = append(args, v)
+			str.WriteString(db2go.Placeholder(dbType, len(args)))
+		}
+		str.WriteByte(')')
+	}
+	_, err := conn.ExecContext(context.Background(), str.String(), args...)
+	if err != nil {
+		return fmt.Errorf("table '%s': %v", b.table.Name(), err)
+	}
+	return nil
+}
+
+// 在同一个连接上执行，执行期间关闭外键检查
+func withConn(db *sql.DB, dbType string, fn func(*sql.Conn) error) error {
+	checks, ok := foreignKeyChecks[dbType]
+	if !ok {
+		return fmt.Errorf("unsupported db '%s'", dbType)
+	}
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	_, err = conn.ExecContext(ctx, checks[0])
+	if err != nil {
+		return err
+	}
+	err = fn(conn)
+	// 连接会回到连接池，必须恢复
+	_, err2 := conn.ExecContext(ctx, checks[1])
+	if err != nil {
+		return err
+	}
+	return err2
+}
@@ -0,0 +1,251 @@
+package db2go
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	erMermaidTypeRegexp = regexp.MustCompile(`[^A-Za-z0-9_()\[\]-]+`)
+	erAliasRegexp       = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+)
+
+// ER图的选项
+type EROption struct {
+	Tables []string // 只输出这些表和它们的邻居，空表示所有的表
+	Hops   int      // 邻居的跳数，外键关系，两个方向都算
+}
+
+// 外键关系
+type erRelation struct {
+	table  *Table  // 子表
+	column *Column // 子表的外键列
+	ref    *ForeignTable
+}
+
+// 从tables开始，沿着外键（两个方向）找出hops跳以内的所有表，顺序与Schema.Tables()一致
+func (s *Schema) Neighbours(tables []string, hops int) ([]*Table, error) {
+	selected := make(map[*Table]bool)
+	var current []*Table
+	for _, name := range tables {
+		t := s.GetTable(name)
+		if t == nil {
+			return nil, fmt.Errorf("table '%s' not found", name)
+		}
+		selected[t] = true
+		current = append(current, t)
+	}
+	// 无向的邻接表
+	adjacent := make(map[*Table][]*Table)
+	for _, t := range s.table {
+		for _, c := range t.column {
+			if c.foreignTable == nil || c.foreignTable.table == nil {
+				continue
+			}
+			ref := c.foreignTable.table
+			adjacent[t] = append(adjacent[t], ref)
+			adjacent[ref] = append(adjacent[ref], t)
+		}
+	}
+	for i := 0; i < hops && len(current) > 0; i++ {
+		var next []*Table
+		for _, t := range current {
+			for _, n := range adjacent[t] {
+				if !selected[n] {
+					selected[n] = true
+					next = append(next, n)
+				}
+			}
+		}
+		current = next
+	}
+	var result []*Table
+	for _, t := range s.table {
+		if selected[t] {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+// 根据选项找出表和表之间的关系
+func (o *EROption) tables(s *Schema) ([]*Table, []*erRelation, error) {
+	tables := s.table
+	if o != nil && len(o.Tables) > 0 {
+		var err error
+		tables, err = s.Neighbours(o.Tables, o.Hops)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	selected := make(map[*Table]bool)
+	for _, t := range tables {
+		selected[t] = true
+	}
+	var relations []*erRelation
+	for _, t := range tables {
+		for _, c := range t.column {
+			if c.foreignTable == nil || !selected[c.foreignTable.table] {
+				continue
+			}
+			relations = append(relations, &erRelation{table: t, column: c, ref: c.foreignTable})
+		}
+	}
+	return tables, relations, nil
+}
+
+// 列的键标记，PK/FK/UK
+func erColumnKeys(c *Column) []string {
+	var keys []string
+	if c.primaryKey {
+		keys = append(keys, "PK")
+	}
+	if c.foreignTable != nil {
+		keys = append(keys, "FK")
+	}
+	if c.unique {
+		keys = append(keys, "UK")
+	}
+	return keys
+}
+
+// 输出Graphviz DOT
+func WriteDOT(w io.Writer, s *Schema, opt *EROption) error {
+	tables, relations, err := opt.tables(s)
+	if err != nil {
+		return err
+	}
+	var str strings.Builder
+	fmt.Fprintf(&str, "digraph %q {\n", s.name)
+	str.WriteString("\trankdir=LR;\n")
+	str.WriteString("\tnode [shape=plaintext, fontname=\"Helvetica\"];\n")
+	str.WriteString("\tedge [fontsize=10];\n")
+	for _, t := range tables {
+		fmt.Fprintf(&str, "\t%q [label=<<table border=\"0\" cellborder=\"1\" cellspacing=\"0\">", t.name)
+		fmt.Fprintf(&str, "<tr><td bgcolor=\"lightgrey\" colspan=\"2\"><b>%s</b></td></tr>", html.EscapeString(t.name))
+		for _, c := range t.column {
+			name := html.EscapeString(c.name)
+			if c.primaryKey {
+				name = "<u>" + name + "</u>"
+			}
+			keys := strings.Join(erColumnKeys(c), ",")
+			if keys != "" {
+				keys = " " + keys
+			}
+			fmt.Fprintf(&str, "<tr><td port=%q align=\"left\">%s%s</td><td align=\"left\">%s</td></tr>",
+				c.name, name, keys, html.EscapeString(c._type))
+		}
+		str.WriteString("</table>>];\n")
+	}
+	for _, r := range relations {
+		fmt.Fprintf(&str, "\t%q:%q -> %q:%q [label=%q];\n",
+			r.table.name, r.column.name, r.ref.table.name, r.ref.column.name, r.column.name)
+	}
+	str.WriteString("}\n")
+	_, err = io.WriteString(w, str.String())
+	return err
+}
+
+// 输出Mermaid erDiagram
+func WriteMermaid(w io.Writer, s *Schema, opt *EROption) error {
+	tables, relations, err := opt.tables(s)
+	if err != nil {
+		return err
+	}
+	var str strings.Builder
+	str.WriteString("erDiagram\n")
+	for _, t := range tables {
+		fmt.Fprintf(&str, "    %s {\n", erAlias(t.name))
+		for _, c := range t.column {
+			fmt.Fprintf(&str, "        %s %s", erMermaidTypeRegexp.ReplaceAllString(c._type, "_"), erAlias(c.name))
+			keys := erColumnKeys(c)
+			if len(keys) > 0 {
+				str.WriteByte(' ')
+				str.WriteString(strings.Join(keys, ","))
+			}
+			if c.comment != "" {
+				fmt.Fprintf(&str, " %q", strings.ReplaceAll(c.comment, `"`, "'"))
+			}
+			str.WriteByte('\n')
+		}
+		str.WriteString("    }\n")
+	}
+	for _, r := range relations {
+		fmt.Fprintf(&str, "    %s %s--%s %s : %q\n",
+			erAlias(r.table.name), erManySide(r.column), erOneSide(r.column), erAlias(r.ref.table.name), r.column.name)
+	}
+	_, err = io.WriteString(w, str.String())
+	return err
+}
+
+// 输出PlantUML
+func WritePlantUML(w io.Writer, s *Schema, opt *EROption) error {
+	tables, relations, err := opt.tables(s)
+	if err != nil {
+		return err
+	}
+	var str strings.Builder
+	str.WriteString("@startuml\n")
+	str.WriteString("hide circle\n")
+	str.WriteString("skinparam linetype ortho\n")
+	for _, t := range tables {
+		fmt.Fprintf(&str, "\nentity %q as %s {\n", t.name, erAlias(t.name))
+		pk, npk := t.PrimaryKeyColumns()
+		for _, c := range pk {
+			fmt.Fprintf(&str, "  * %s : %s%s\n", c.name, c._type, erPlantUMLKeys(c))
+		}
+		str.WriteString("  --\n")
+		for _, c := range npk {
+			mark := ""
+			if !c.nullable {
+				mark = "* "
+			}
+			fmt.Fprintf(&str, "  %s%s : %s%s\n", mark, c.name, c._type, erPlantUMLKeys(c))
+		}
+		str.WriteString("}\n")
+	}
+	if len(relations) > 0 {
+		str.WriteByte('\n')
+	}
+	for _, r := range relations {
+		fmt.Fprintf(&str, "%s %s--%s %s : %s\n",
+			erAlias(r.table.name), erManySide(r.column), erOneSide(r.column), erAlias(r.ref.table.name), r.column.name)
+	}
+	str.WriteString("@enduml\n")
+	_, err = io.WriteString(w, str.String())
+	return err
+}
+
+func erPlantUMLKeys(c *Column) string {
+	var str strings.Builder
+	for _, k := range erColumnKeys(c) {
+		str.WriteString(" <<")
+		str.WriteString(k)
+		str.WriteString(">>")
+	}
+	return str.String()
+}
+
+// 子表这一边，外键列唯一则是一对一
+func erManySide(c *Column) string {
+	if c.primaryKey || c.unique {
+		return "|o"
+	}
+	return "}o"
+}
+
+// 父表这一边，外键列可以为null则是0或1
+func erOneSide(c *Column) string {
+	if c.nullable {
+		return "o|"
+	}
+	return "||"
+}
+
+// Mermaid和PlantUML的名称只能是字母数字下划线
+func erAlias(name string) string {
+	return erAliasRegexp.ReplaceAllString(name, "_")
+}
@@ -0,0 +1,64 @@
+package db2go
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNeighbours(t *testing.T) {
+	s := testSchema()
+	tables, err := s.Neighbours([]string{"t1"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables[0].Name() != "t1" {
+		t.FailNow()
+	}
+	tables, err = s.Neighbours([]string{"t1"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 2 || tables[1].Name() != "t3" {
+		t.FailNow()
+	}
+	tables, err = s.Neighbours([]string{"t1"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 3 {
+		t.FailNow()
+	}
+	_, err = s.Neighbours([]string{"t0"}, 1)
+	if err == nil {
+		t.FailNow()
+	}
+}
+
+func TestWriteER(t *testing.T) {
+	s := testSchema()
+	opt := &EROption{Tables: []string{"t3"}, Hops: 1}
+	var str strings.Builder
+	err := WriteDOT(&str, s, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(str.String(), `"t3":"t1_id" -> "t1":"id" [label="t1_id"];`) || strings.Contains(str.String(), `"T4"`) {
+		t.Fatal(str.String())
+	}
+	str.Reset()
+	err = WriteMermaid(&str, s, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(str.String(), `t3 }o--o| t1 : "t1_id"`) || !strings.Contains(str.String(), "varchar(32) name UK") {
+		t.Fatal(str.String())
+	}
+	str.Reset()
+	err = WritePlantUML(&str, s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(str.String(), `entity "T4" as T4 {`) || !strings.Contains(str.String(), "t3 }o--o| t2 : t2") {
+		t.Fatal(str.String())
+	}
+}
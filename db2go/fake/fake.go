@@ -0,0 +1,300 @@
+/*
+根据数据库结构生成假数据，用于压力测试
+*/
+package fake
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxRetry = 100 // 唯一键冲突时重试的次数
+	nullRate = 10  // 可为null的列，有10%的概率为null
+)
+
+var (
+	errEmptyTable = errors.New("table has no insertable columns")
+)
+
+// 假数据生成器，相同的seed和结构生成相同的数据
+type Generator struct {
+	schema  *db2go.Schema
+	rand    *rand.Rand
+	rows    map[string]int                   // 单独设置的表的行数
+	skip    map[string]bool                  // 不生成的表
+	values  map[*db2go.Column][]interface{}  // 已经插入的值，用于外键
+	uniques map[*db2go.Index]map[string]bool // 已经生成的唯一键
+	indexes map[*db2go.Table][]*db2go.Index  // 表的唯一索引
+	seq     map[*db2go.Table]int             // 已经生成的行数
+}
+
+func NewGenerator(schema *db2go.Schema, seed int64) *Generator {
+	g := new(Generator)
+	g.schema = schema
+	g.rand = rand.New(rand.NewSource(seed))
+	g.rows = make(map[string]int)
+	g.skip = make(map[string]bool)
+	g.values = make(map[*db2go.Column][]interface{})
+	g.uniques = make(map[*db2go.Index]map[string]bool)
+	g.indexes = make(map[*db2go.Table][]*db2go.Index)
+	g.seq = make(map[*db2go.Table]int)
+	return g
+}
+
+// 单独设置表的行数，0表示不生成
+func (g *Generator) SetRows(table string, rows int) {
+	if rows < 1 {
+		g.skip[table] = true
+		return
+	}
+	g.rows[table] = rows
+}
+
+// 按外键依赖的顺序，每个表插入rows行
+func (g *Generator) Insert(db *sql.DB, rows int) error {
+	for _, t := range g.schema.SortedTables() {
+		if g.skip[t.Name()] {
+			continue
+		}
+		n, ok := g.rows[t.Name()]
+		if !ok {
+			n = rows
+		}
+		err := g.InsertTable(db, t, n)
+		if err != nil {
+			return fmt.Errorf("table '%s': %v", t.Name(), err)
+		}
+	}
+	return nil
+}
+
+// 插入一个表的rows行，被引用的表需要先插入
+func (g *Generator) InsertTable(db *sql.DB, table *db2go.Table, rows int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	stmts := make(map[string]*sql.Stmt)
+	defer func() {
+		for _, s := range stmts {
+			_ = s.Close()
+		}
+	}()
+	for i := 0; i < rows; i++ {
+		columns, values, err := g.Row(table)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		query := g.insertSQL(table, columns)
+		stmt, ok := stmts[query]
+		if !ok {
+			stmt, err = tx.Prepare(query)
+			if err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+			stmts[query] = stmt
+		}
+		res, err := stmt.Exec(values...)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		g.saveValues(table, columns, values, res)
+	}
+	return tx.Commit()
+}
+
+// 生成一行数据，返回的列不包括自增列
+func (g *Generator) Row(table *db2go.Table) ([]*db2go.Column, []interface{}, error) {
+	var columns []*db2go.Column
+	for _, c := range table.Columns() {
+		if !c.IsAutoIncrement() {
+			columns = append(columns, c)
+		}
+	}
+	if len(columns) < 1 {
+		return nil, nil, errEmptyTable
+	}
+	seq := g.seq[table] + 1
+	indexes := g.uniqueIndexes(table)
+	for retry := 0; retry < maxRetry; retry++ {
+		values := make([]interface{}, len(columns))
+		for i, c := range columns {
+			v, err := g.value(table, c, seq, retry)
+			if err != nil {
+				return nil, nil, err
+			}
+			values[i] = v
+		}
+		keys, ok := g.checkUnique(indexes, columns, values)
+		if !ok {
+			continue
+		}
+		for i, k := range keys {
+			if k != "" {
+				g.uniques[indexes[i]][k] = true
+			}
+		}
+		g.seq[table] = seq
+		return columns, values, nil
+	}
+	return nil, nil, fmt.Errorf("can't generate unique row after %d retries", maxRetry)
+}
+
+// 生成一个列的值
+func (g *Generator) value(table *db2go.Table, c *db2go.Column, seq, retry int) (interface{}, error) {
+	// 外键，从被引用的表已经插入的值中选
+	if ft := c.ForeignTable(); ft != nil {
+		values := g.values[ft.Column()]
+		if len(values) < 1 || (c.IsNullable() && g.rand.Intn(100) < nullRate) {
+			if c.IsNullable() {
+				return nil, nil
+			}
+			// 自引用的第一行
+			if ft.Table() == table {
+				return nil, fmt.Errorf("column '%s' references itself and is not nullable", c.Name())
+			}
+			return nil, fmt.Errorf("column '%s' references '%s.%s' which has no rows", c.Name(), ft.Table().Name(), ft.Column().Name())
+		}
+		return values[g.rand.Intn(len(values))], nil
+	}
+	if c.IsNullable() && !c.IsPrimaryKey() && g.rand.Intn(100) < nullRate {
+		return nil, nil
+	}
+	v := value(g.rand, c, seq)
+	// 唯一的字符串加上序号，减少冲突
+	if s, ok := v.(string); ok && g.isUnique(table, c) {
+		suffix := "_" + strconv.Itoa(seq)
+		if retry > 0 {
+			suffix += "_" + strconv.Itoa(retry)
+		}
+		n := c.Length()
+		if n > 0 && len(s)+len(suffix) > n {
+			if len(suffix) > n {
+				suffix = suffix[len(suffix)-n:]
+			}
+			s = s[:n-len(suffix)]
+		}
+		v = s + suffix
+	}
+	return v, nil
+}
+
+// 列是否在唯一索引中
+func (g *Generator) isUnique(table *db2go.Table, c *db2go.Column) bool {
+	for _, i := range g.uniqueIndexes(table) {
+		for _, ic := range i.Columns() {
+			if ic == c {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 表的所有唯一索引，包括主键，没有索引信息时使用列的unique和主键
+func (g *Generator) uniqueIndexes(table *db2go.Table) []*db2go.Index {
+	indexes, ok := g.indexes[table]
+	if ok {
+		return indexes
+	}
+	for _, i := range table.Indexes() {
+		if i.IsUnique() {
+			indexes = append(indexes, i)
+		}
+	}
+	if len(table.Indexes()) < 1 {
+		for _, c := range table.Columns() {
+			if c.IsUnique() || c.IsPrimaryKey() {
+				indexes = append(indexes, db2go.NewIndex(c.Name(), true, c.IsPrimaryKey(), c))
+			}
+		}
+	}
+	for _, i := range indexes {
+		g.uniques[i] = make(map[string]bool)
+	}
+	g.indexes[table] = indexes
+	return indexes
+}
+
+// 检查唯一键是否冲突，返回每个索引的键，有null的键为空
+func (g *Generator) checkUnique(indexes []*db2go.Index, columns []*db2go.Column, values []interface{}) ([]string, bool) {
+	keys := make([]string, len(indexes))
+	for i, index := range indexes {
+		var key strings.Builder
+		null := false
+		for _, ic := range index.Columns() {
+			found := false
+			for k, c := range columns {
+				if c == ic {
+					if values[k] == nil {
+						null = true
+					}
+					fmt.Fprintf(&key, "%v\x00", values[k])
+					found = true
+					break
+				}
+			}
+			// 自增列，一定是唯一的
+			if !found {
+				null = true
+			}
+		}
+		if null {
+			continue
+		}
+		if g.uniques[index][key.String()] {
+			return nil, false
+		}
+		keys[i] = key.String()
+	}
+	return keys, true
+}
+
+// 保存插入的值，用于外键
+func (g *Generator) saveValues(table *db2go.Table, columns []*db2go.Column, values []interface{}, res sql.Result) {
+	for i, c := range columns {
+		if values[i] != nil {
+			g.values[c] = append(g.values[c], values[i])
+		}
+	}
+	for _, c := range table.Columns() {
+		if c.IsAutoIncrement() {
+			id, err := res.LastInsertId()
+			if err == nil {
+				g.values[c] = append(g.values[c], id)
+			}
+		}
+	}
+}
+
+func (g *Generator) insertSQL(table *db2go.Table, columns []*db2go.Column) string {
+	dbType := g.schema.DBType()
+	var str strings.Builder
+	str.WriteString("insert into ")
+	str.WriteString(db2go.QuoteName(dbType, table.Name()))
+	str.WriteString("(")
+	for i, c := range columns {
+		if i > 0 {
+			str.WriteByte(',')
+		}
+		str.WriteString(db2go.QuoteName(dbType, c.Name()))
+	}
+	str.WriteString(") values(")
+	for i := range columns {
+		if i > 0 {
+			str.WriteByte(',')
+		}
+		str.WriteString(db2go.Placeholder(dbType, i+1))
+	}
+	str.WriteString(")")
+	return str.String()
+}
@@ -0,0 +1,81 @@
+package fake
+
+import (
+	"github.com/qq51529210/db/db2go"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 读取testdata/schema.json的结构
+func testSchema(t *testing.T) *db2go.Schema {
+	s, err := db2go.ReadSchemaJSONFile("testdata/schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+type testResult int64
+
+func (r testResult) LastInsertId() (int64, error) {
+	return int64(r), nil
+}
+
+func (r testResult) RowsAffected() (int64, error) {
+	return 1, nil
+}
+
+func testGenerate(t *testing.T, seed int64) [][]interface{} {
+	s := testSchema(t)
+	g := NewGenerator(s, seed)
+	var rows [][]interface{}
+	// 被引用的表没有数据
+	_, _, err := g.Row(s.GetTable("t2"))
+	if err == nil {
+		t.FailNow()
+	}
+	t1 := s.GetTable("t1")
+	names := make(map[interface{}]bool)
+	for i := 1; i <= 100; i++ {
+		columns, values, err := g.Row(t1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(columns) != 3 || columns[0].Name() != "name" {
+			t.FailNow()
+		}
+		name := values[0].(string)
+		if len(name) > 8 || names[name] {
+			t.Fatal(name)
+		}
+		names[name] = true
+		if values[1] != "on" && values[1] != "off" {
+			t.Fatal(values[1])
+		}
+		if values[2] != nil && len(values[2].(string)) > 6 {
+			t.Fatal(values[2])
+		}
+		g.saveValues(t1, columns, values, testResult(i))
+		rows = append(rows, values)
+	}
+	t2 := s.GetTable("t2")
+	for i := 1; i <= 100; i++ {
+		_, values, err := g.Row(t2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := values[0].(int64)
+		if id < 1 || id > 100 || !strings.Contains(values[1].(string), "@") {
+			t.Fatal(values)
+		}
+		rows = append(rows, values)
+	}
+	return rows
+}
+
+func TestGenerator(t *testing.T) {
+	if !reflect.DeepEqual(testGenerate(t, 1), testGenerate(t, 1)) {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,203 @@
+package fake
+
+import (
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	words = []string{
+		"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel", "india", "juliet",
+		"kilo", "lima", "mike", "november", "oscar", "papa", "quebec", "romeo", "sierra", "tango",
+		"uniform", "victor", "whiskey", "xray", "yankee", "zulu",
+	}
+	firstNames = []string{"james", "mary", "john", "linda", "wei", "fang", "li", "na", "ahmed", "sofia", "lucas", "emma"}
+	lastNames  = []string{"smith", "wang", "zhang", "garcia", "mueller", "rossi", "kim", "tanaka", "silva", "brown"}
+	domains    = []string{"example.com", "example.org", "example.net"}
+	timeBegin  = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	timeEnd    = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	// timestamp最大到2038年
+	timestampEnd = time.Date(2038, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+)
+
+// 整数类型的范围
+func intRange(c *db2go.Column) (int64, uint64) {
+	var bits uint
+	switch c.DataType() {
+	case "tinyint", "bool", "boolean":
+		bits = 8
+	case "smallint":
+		bits = 16
+	case "mediumint":
+		bits = 24
+	case "int", "integer":
+		bits = 32
+	default:
+		bits = 64
+	}
+	if c.IsUnsigned() {
+		if bits == 64 {
+			return 0, math.MaxUint64
+		}
+		return 0, 1<<bits - 1
+	}
+	return -(1 << (bits - 1)), 1<<(bits-1) - 1
+}
+
+// 生成列的随机值，不考虑null，唯一和外键
+func value(r *rand.Rand, c *db2go.Column, seq int) interface{} {
+	typ := c.DataType()
+	switch typ {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "bool", "boolean":
+		min, max := intRange(c)
+		// tinyint(1)一般是bool
+		if typ == "bool" || typ == "boolean" || (typ == "tinyint" && c.Length() == 1) {
+			return r.Intn(2)
+		}
+		// 不需要太大的数
+		if max > 1000000 {
+			max = 1000000
+		}
+		if min < 0 {
+			min = 0
+		}
+		return min + r.Int63n(int64(max)-min+1)
+	case "decimal", "numeric":
+		precision, scale := 10, 0
+		args := c.TypeArgs()
+		if len(args) > 0 {
+			precision, _ = strconv.Atoi(args[0])
+		}
+		if len(args) > 1 {
+			scale, _ = strconv.Atoi(args[1])
+		}
+		digits := precision - scale
+		if digits > 6 {
+			digits = 6
+		}
+		n := r.Float64() * math.Pow10(digits)
+		if c.IsUnsigned() || n < 0 {
+			n = math.Abs(n)
+		}
+		s := strconv.FormatFloat(n, 'f', scale, 64)
+		// 四舍五入后可能多一位
+		if digits > 0 && len(strings.Split(s, ".")[0]) > digits {
+			s = strconv.FormatFloat(0, 'f', scale, 64)
+		}
+		return s
+	case "float", "double", "real":
+		return math.Round(r.Float64()*100000) / 100
+	case "bit":
+		n := c.Length()
+		if n < 1 {
+			n = 1
+		}
+		if n > 62 {
+			n = 62
+		}
+		return r.Int63n(1 << uint(n))
+	case "date":
+		return randomTime(r, timeEnd).Format("2006-01-02")
+	case "datetime":
+		return randomTime(r, timeEnd).Format("2006-01-02 15:04:05")
+	case "timestamp":
+		return randomTime(r, timestampEnd).Format("2006-01-02 15:04:05")
+	case "time":
+		return fmt.Sprintf("%02d:%02d:%02d", r.Intn(24), r.Intn(60), r.Intn(60))
+	case "year":
+		return 1970 + r.Intn(130)
+	case "enum":
+		args := c.TypeArgs()
+		if len(args) < 1 {
+			return ""
+		}
+		return args[r.Intn(len(args))]
+	case "set":
+		var picked []string
+		for _, a := range c.TypeArgs() {
+			if r.Intn(2) == 0 {
+				picked = append(picked, a)
+			}
+		}
+		return strings.Join(picked, ",")
+	case "json":
+		return fmt.Sprintf(`{"id":%d,"name":%q}`, seq, words[r.Intn(len(words))])
+	case "binary":
+		return randomBytes(r, c.Length(), c.Length())
+	case "varbinary":
+		return randomBytes(r, 1, c.Length())
+	case "tinyblob", "blob", "mediumblob", "longblob":
+		return randomBytes(r, 1, 64)
+	case "char", "varchar", "tinytext", "text", "mediumtext", "longtext":
+		n := c.Length()
+		if n < 1 {
+			switch typ {
+			case "char":
+				n = 1
+			case "tinytext":
+				n = 255
+			default:
+				n = 200
+			}
+		}
+		return text(r, c.Name(), n, seq)
+	default:
+		return text(r, c.Name(), 16, seq)
+	}
+}
+
+func randomTime(r *rand.Rand, end int64) time.Time {
+	return time.Unix(timeBegin+r.Int63n(end-timeBegin), 0).UTC()
+}
+
+func randomBytes(r *rand.Rand, min, max int) []byte {
+	if max < 1 {
+		max = 1
+	}
+	if min > max {
+		min = max
+	}
+	n := min
+	if max > min {
+		n += r.Intn(max - min + 1)
+	}
+	b := make([]byte, n)
+	_, _ = r.Read(b)
+	return b
+}
+
+// 根据列名生成看起来真实的字符串，最长n个字符
+func text(r *rand.Rand, name string, n, seq int) string {
+	name = strings.ToLower(name)
+	var s string
+	switch {
+	case strings.Contains(name, "email") || strings.Contains(name, "mail"):
+		s = fmt.Sprintf("%s.%s%d@%s", firstNames[r.Intn(len(firstNames))], lastNames[r.Intn(len(lastNames))], seq, domains[r.Intn(len(domains))])
+	case strings.Contains(name, "phone") || strings.Contains(name, "mobile") || strings.Contains(name, "tel"):
+		s = fmt.Sprintf("1%010d", r.Int63n(10000000000))
+	case strings.Contains(name, "url") || strings.Contains(name, "link"):
+		s = fmt.Sprintf("https://%s/%s/%d", domains[r.Intn(len(domains))], words[r.Intn(len(words))], seq)
+	case strings.HasSuffix(name, "name") || name == "nickname" || name == "username":
+		s = fmt.Sprintf("%s_%s", firstNames[r.Intn(len(firstNames))], lastNames[r.Intn(len(lastNames))])
+	case n <= 16:
+		s = words[r.Intn(len(words))]
+	default:
+		var str strings.Builder
+		for str.Len() < n/2 {
+			if str.Len() > 0 {
+				str.WriteByte(' ')
+			}
+			str.WriteString(words[r.Intn(len(words))])
+		}
+		s = str.String()
+	}
+	if len(s) > n {
+		s = s[:n]
+	}
+	return s
+}
@@ -0,0 +1,132 @@
+package db2go
+
+import (
+	"sort"
+	"strings"
+)
+
+// 表的列和类型，table->column->type，用于检查数据库结构是否与生成代码时一致
+type Fingerprint map[string]map[string]string
+
+// 表的指纹，columns为空表示所有的列
+func (s *Schema) Fingerprint(table string, columns ...string) Fingerprint {
+	fp := make(Fingerprint)
+	t := s.GetTable(table)
+	if t == nil {
+		return fp
+	}
+	cs := make(map[string]string)
+	if len(columns) < 1 {
+		for _, c := range t.column {
+			cs[c.name] = c._type
+		}
+	} else {
+		for _, name := range columns {
+			if c := t.GetColumn(name); c != nil {
+				cs[c.name] = c._type
+			}
+		}
+	}
+	fp[table] = cs
+	return fp
+}
+
+// 只记录表，不记录列，用于只检查表是否存在，比如count(*)
+func (fp Fingerprint) AddTable(table string) {
+	if _, ok := fp[table]; !ok {
+		fp[table] = make(map[string]string)
+	}
+}
+
+// 合并另一个指纹
+func (fp Fingerprint) Merge(other Fingerprint) {
+	for t, cs := range other {
+		m, ok := fp[t]
+		if !ok {
+			m = make(map[string]string)
+			fp[t] = m
+		}
+		for c, typ := range cs {
+			m[c] = typ
+		}
+	}
+}
+
+// 与指纹比较，返回表或列被删除（或改名），列的类型改变，新增的表和列不算
+func (s *Schema) FingerprintChanges(fp Fingerprint) []*SchemaChange {
+	var changes []*SchemaChange
+	tables := make([]string, 0, len(fp))
+	for t := range fp {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	for _, name := range tables {
+		t := s.GetTable(name)
+		if t == nil {
+			changes = append(changes, &SchemaChange{Type: TableRemoved, Table: name})
+			continue
+		}
+		columns := make([]string, 0, len(fp[name]))
+		for c := range fp[name] {
+			columns = append(columns, c)
+		}
+		sort.Strings(columns)
+		for _, cn := range columns {
+			typ := fp[name][cn]
+			c := t.GetColumn(cn)
+			if c == nil {
+				changes = append(changes, &SchemaChange{Type: ColumnRemoved, Table: name, Column: cn, From: typ})
+				continue
+			}
+			if !strings.EqualFold(c._type, typ) {
+				changes = append(changes, &SchemaChange{Type: ColumnChanged, Table: name, Column: cn, From: typ, To: c._type})
+			}
+		}
+	}
+	return changes
+}
+
+// 检查指纹，funcs是每个函数依赖的表，用于在错误信息中显示受影响的函数
+func (s *Schema) CheckFingerprint(fp Fingerprint, funcs map[string][]string) error {
+	changes := s.FingerprintChanges(fp)
+	if len(changes) < 1 {
+		return nil
+	}
+	return &FingerprintError{Changes: changes, funcs: funcs}
+}
+
+// 数据库结构与指纹不一致
+type FingerprintError struct {
+	Changes []*SchemaChange
+	funcs   map[string][]string
+}
+
+func (e *FingerprintError) Error() string {
+	var str strings.Builder
+	str.WriteString("schema mismatch:")
+	for _, c := range e.Changes {
+		str.WriteString("\n  ")
+		str.WriteString(c.String())
+		if fs := e.Funcs(c.Table); len(fs) > 0 {
+			str.WriteString(" (used by ")
+			str.WriteString(strings.Join(fs, ", "))
+			str.WriteString(")")
+		}
+	}
+	return str.String()
+}
+
+// 依赖table的函数
+func (e *FingerprintError) Funcs(table string) []string {
+	var names []string
+	for f, tables := range e.funcs {
+		for _, t := range tables {
+			if t == table {
+				names = append(names, f)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
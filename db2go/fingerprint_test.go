@@ -0,0 +1,37 @@
+package db2go
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	s := testSchema()
+	fp := s.Fingerprint("t1")
+	fp.Merge(s.Fingerprint("t3", "id", "t1_id", "not_exists"))
+	fp.AddTable("t1")
+	fp.AddTable("t2")
+	if len(fp["t1"]) != 2 || len(fp["t3"]) != 2 || fp["t2"] == nil || len(fp["t2"]) != 0 {
+		t.Fatal(fp)
+	}
+	funcs := map[string][]string{"GetT1": {"t1"}, "GetT3": {"t1", "t3"}, "CountT2": {"t2"}}
+	if err := s.CheckFingerprint(fp, funcs); err != nil {
+		t.Fatal(err)
+	}
+	other := make(Fingerprint)
+	other.AddTable("not_exists")
+	if err := s.CheckFingerprint(other, map[string][]string{"CountNotExists": {"not_exists"}}); err == nil ||
+		!strings.Contains(err.Error(), "not_exists") {
+		t.Fatal(err)
+	}
+	s.GetTable("t1").GetColumn("name")._type = "varchar(64)"
+	s.GetTable("t3").column = s.GetTable("t3").column[:1]
+	err := s.CheckFingerprint(fp, funcs)
+	if err == nil {
+		t.FailNow()
+	}
+	if !strings.Contains(err.Error(), "column-changed t1.name: varchar(32) -> varchar(64) (used by GetT1, GetT3)") ||
+		!strings.Contains(err.Error(), "column-removed t3.t1_id: int (used by GetT3)") {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,306 @@
+/*
+集成测试的数据，每个表一个yaml/json文件，文件名就是表名，内容是行的数组，比如user.yml
+  - id: 1
+    name: a
+  - id: 2
+    name: b
+*/
+package fixture
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// 关闭和打开外键检查
+	foreignKeyChecks = map[string][2]string{
+		db2go.MYSQL: {"set foreign_key_checks=0", "set foreign_key_checks=1"},
+	}
+	// 清空表
+	truncateFormat = map[string]string{
+		db2go.MYSQL: "truncate table %s",
+	}
+)
+
+// 一个表的数据
+type tableRows struct {
+	table   *db2go.Table
+	file    string
+	columns []*db2go.Column          // 所有行用到的列
+	rows    []map[string]interface{} // 每一行
+}
+
+// 加载的测试数据
+type Fixtures struct {
+	schema *db2go.Schema
+	tables []*tableRows // 按外键依赖排序
+}
+
+// 加载目录下的所有.yml，.yaml，.json文件
+func Load(schema *db2go.Schema, dir string) (*Fixtures, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(info.Name())) {
+		case ".yml", ".yaml", ".json":
+			files = append(files, filepath.Join(dir, info.Name()))
+		}
+	}
+	return LoadFiles(schema, files...)
+}
+
+// 加载文件，文件名（不包括扩展名）是表名
+func LoadFiles(schema *db2go.Schema, files ...string) (*Fixtures, error) {
+	loaded := make(map[*db2go.Table]*tableRows)
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		table := schema.GetTable(name)
+		if table == nil {
+			return nil, fmt.Errorf("%s: table '%s' not found", file, name)
+		}
+		if t, ok := loaded[table]; ok {
+			return nil, fmt.Errorf("%s: table '%s' already loaded from %s", file, name, t.file)
+		}
+		rows, err := readFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		t := &tableRows{table: table, file: file, rows: rows}
+		err = t.check()
+		if err != nil {
+			return nil, err
+		}
+		loaded[table] = t
+	}
+	f := &Fixtures{schema: schema}
+	for _, t := range schema.SortedTables() {
+		if rows, ok := loaded[t]; ok {
+			f.tables = append(f.tables, rows)
+		}
+	}
+	return f, nil
+}
+
+// 读取行
+func readFile(file string) ([]map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]interface{}
+	if strings.ToLower(filepath.Ext(file)) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&rows)
+	} else {
+		err = yaml.Unmarshal(data, &rows)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 嵌套的值转换成json，用于json类型的列
+	for _, row := range rows {
+		for k, v := range row {
+			switch v.(type) {
+			case map[interface{}]interface{}, map[string]interface{}, []interface{}:
+				data, err := json.Marshal(jsonValue(v))
+				if err != nil {
+					return nil, err
+				}
+				row[k] = string(data)
+			}
+		}
+	}
+	return rows, nil
+}
+
+// yaml的map[interface{}]interface{}不能转换成json
+func jsonValue(v interface{}) interface{} {
+	switch m := v.(type) {
+	case map[interface{}]interface{}:
+		n := make(map[string]interface{})
+		for k, v := range m {
+			n[fmt.Sprint(k)] = jsonValue(v)
+		}
+		return n
+	case map[string]interface{}:
+		for k, v := range m {
+			m[k] = jsonValue(v)
+		}
+	case []interface{}:
+		for i, v := range m {
+			m[i] = jsonValue(v)
+		}
+	}
+	return v
+}
+
+// 检查字段是否都是表的列
+func (t *tableRows) check() error {
+	used := make(map[string]bool)
+	for i, row := range t.rows {
+		for k := range row {
+			if t.table.GetColumn(k) == nil {
+				return fmt.Errorf("%s: row %d: unknown column '%s' in table '%s'%s", t.file, i+1, k, t.table.Name(), suggest(t.table, k))
+			}
+			used[k] = true
+		}
+	}
+	// 按表的列顺序
+	for _, c := range t.table.Columns() {
+		if used[c.Name()] {
+			t.columns = append(t.columns, c)
+		}
+	}
+	return nil
+}
+
+// 找出相似的列名
+func suggest(table *db2go.Table, name string) string {
+	var names []string
+	for _, c := range table.Columns() {
+		if strings.EqualFold(c.Name(), name) || strings.Contains(c.Name(), name) || strings.Contains(name, c.Name()) {
+			names = append(names, c.Name())
+		}
+	}
+	if len(names) < 1 {
+		for _, c := range table.Columns() {
+			names = append(names, c.Name())
+		}
+		return fmt.Sprintf(", columns are %s", strings.Join(names, ", "))
+	}
+	return fmt.Sprintf(", did you mean %s", strings.Join(names, ", "))
+}
+
+// 涉及的表名
+func (f *Fixtures) Tables() []string {
+	var names []string
+	for _, t := range f.tables {
+		names = append(names, t.table.Name())
+	}
+	return names
+}
+
+// 是否需要关闭外键检查，有自引用或者循环引用时，插入的顺序无法满足外键
+func (f *Fixtures) needDisableForeignKey() bool {
+	order := make(map[*db2go.Table]int)
+	for i, t := range f.schema.SortedTables() {
+		order[t] = i
+	}
+	for _, t := range f.tables {
+		for _, c := range t.table.Columns() {
+			ft := c.ForeignTable()
+			if ft != nil && order[ft.Table()] >= order[t.table] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 清空涉及的表，然后按外键依赖的顺序插入数据
+func (f *Fixtures) Insert(db *sql.DB) error {
+	err := f.Clean(db)
+	if err != nil {
+		return err
+	}
+	return f.withConn(db, f.needDisableForeignKey(), func(conn *sql.Conn) error {
+		for _, t := range f.tables {
+			err := f.insert(conn, t)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// 清空涉及的表，用于测试之间，被引用的表不能truncate，所以总是关闭外键检查
+func (f *Fixtures) Clean(db *sql.DB) error {
+	return f.withConn(db, true, f.truncate)
+}
+
+func (f *Fixtures) truncate(conn *sql.Conn) error {
+	format, ok := truncateFormat[f.schema.DBType()]
+	if !ok {
+		return fmt.Errorf("unsupported db '%s'", f.schema.DBType())
+	}
+	// 被引用的表后清空
+	for i := len(f.tables) - 1; i >= 0; i-- {
+		name := db2go.QuoteName(f.schema.DBType(), f.tables[i].table.Name())
+		_, err := conn.ExecContext(context.Background(), fmt.Sprintf(format, name))
+		if err != nil {
+			return fmt.Errorf("truncate '%s': %v", f.tables[i].table.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (f *Fixtures) insert(conn *sql.Conn, t *tableRows) error {
+	dbType := f.schema.DBType()
+	for i, row := range t.rows {
+		var columns []string
+		var placeholders []string
+		var values []interface{}
+		for _, c := range t.columns {
+			v, ok := row[c.Name()]
+			if !ok {
+				continue
+			}
+			columns = append(columns, db2go.QuoteName(dbType, c.Name()))
+			placeholders = append(placeholders, db2go.Placeholder(dbType, len(placeholders)+1))
+			values = append(values, v)
+		}
+		query := fmt.Sprintf("insert into %s(%s) values(%s)",
+			db2go.QuoteName(dbType, t.table.Name()), strings.Join(columns, ","), strings.Join(placeholders, ","))
+		_, err := conn.ExecContext(context.Background(), query, values...)
+		if err != nil {
+			return fmt.Errorf("%s: row %d: %v", t.file, i+1, err)
+		}
+	}
+	return nil
+}
+
+// 在同一个连接上执行，disableForeignKey表示执行期间关闭外键检查
+func (f *Fixtures) withConn(db *sql.DB, disableForeignKey bool, fn func(*sql.Conn) error) error {
+	checks, ok := foreignKeyChecks[f.schema.DBType()]
+	if !ok {
+		return fmt.Errorf("unsupported db '%s'", f.schema.DBType())
+	}
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if !disableForeignKey {
+		return fn(conn)
+	}
+	_, err = conn.ExecContext(context.Background(), checks[0])
+	if err != nil {
+		return err
+	}
+	err = fn(conn)
+	// 连接会回到连接池，必须恢复
+	_, err2 := conn.ExecContext(context.Background(), checks[1])
+	if err != nil {
+		return err
+	}
+	return err2
+}
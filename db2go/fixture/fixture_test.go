@@ -0,0 +1,61 @@
+package fixture
+
+import (
+	"github.com/qq51529210/db/db2go"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 读取testdata/schema.json的结构
+func testSchema(t *testing.T) *db2go.Schema {
+	s, err := db2go.ReadSchemaJSONFile("testdata/schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestLoad(t *testing.T) {
+	s := testSchema(t)
+	dir, err := ioutil.TempDir("", "fixture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	write := func(name, data string) {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("t1.yml", "- id: 1\n  name: a\n- id: 2\n  name: b\n")
+	write("t2.json", `[{"id": 1, "t1_id": 2, "data": {"a": [1, 2]}}]`)
+	f, err := Load(s, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 被引用的表在前面
+	if strings.Join(f.Tables(), ",") != "t1,t2" || f.needDisableForeignKey() {
+		t.Fatal(f.Tables())
+	}
+	if f.tables[1].rows[0]["data"] != `{"a":[1,2]}` || len(f.tables[1].columns) != 3 {
+		t.Fatal(f.tables[1].rows[0])
+	}
+	// 未知的列
+	write("t1.yml", "- id: 1\n  nmae: a\n")
+	_, err = Load(s, dir)
+	if err == nil || !strings.Contains(err.Error(), "row 1: unknown column 'nmae' in table 't1'") {
+		t.Fatal(err)
+	}
+	// 未知的表
+	write("t3.yml", "- id: 1\n")
+	_, err = LoadFiles(s, filepath.Join(dir, "t3.yml"))
+	if err == nil || !strings.Contains(err.Error(), "table 't3' not found") {
+		t.Fatal(err)
+	}
+}
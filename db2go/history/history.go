@@ -0,0 +1,172 @@
+/*
+结构的历史，结构有变化时在目录中保存一个带版本号的json快照，
+并在CHANGELOG.md中追加一条可读的变化记录，比如
+
+	0001_20200102T150405Z.json
+	0002_20200203T150405Z.json
+	CHANGELOG.md
+*/
+package history
+
+import (
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ChangelogFile = "CHANGELOG.md"
+	timeFormat    = "20060102T150405Z"
+)
+
+var (
+	snapshotRegexp = regexp.MustCompile(`^(\d+)_(\d{8}T\d{6}Z)\.json$`)
+)
+
+// 一个快照
+type Snapshot struct {
+	Version int
+	Time    time.Time
+	File    string
+}
+
+// 快照目录
+type History struct {
+	dir string
+}
+
+// 打开目录，不存在则创建
+func Open(dir string) (*History, error) {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	return &History{dir: dir}, nil
+}
+
+// 所有的快照，按版本排序
+func (h *History) Snapshots() ([]*Snapshot, error) {
+	infos, err := ioutil.ReadDir(h.dir)
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []*Snapshot
+	for _, info := range infos {
+		match := snapshotRegexp.FindStringSubmatch(info.Name())
+		if match == nil || info.IsDir() {
+			continue
+		}
+		s := &Snapshot{File: filepath.Join(h.dir, info.Name())}
+		s.Version, _ = strconv.Atoi(match[1])
+		s.Time, _ = time.Parse(timeFormat, match[2])
+		snapshots = append(snapshots, s)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Version < snapshots[j].Version
+	})
+	return snapshots, nil
+}
+
+// 读取快照
+func (s *Snapshot) Read() (*db2go.Schema, error) {
+	f, err := os.Open(s.File)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return db2go.ReadSchemaJSON(f)
+}
+
+// 最新的快照，没有返回nil
+func (h *History) Latest() (*Snapshot, error) {
+	snapshots, err := h.Snapshots()
+	if err != nil || len(snapshots) < 1 {
+		return nil, err
+	}
+	return snapshots[len(snapshots)-1], nil
+}
+
+// 和最新的快照比较，有变化则保存新的快照并追加变化记录，没有变化返回nil
+func (h *History) Save(schema *db2go.Schema, now time.Time) (*Snapshot, []*db2go.SchemaChange, error) {
+	latest, err := h.Latest()
+	if err != nil {
+		return nil, nil, err
+	}
+	last := new(db2go.Schema)
+	version := 1
+	if latest != nil {
+		last, err = latest.Read()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", latest.File, err)
+		}
+		version = latest.Version + 1
+	}
+	changes := db2go.DiffSchema(last, schema)
+	if latest != nil && len(changes) < 1 {
+		return nil, nil, nil
+	}
+	now = now.UTC()
+	s := &Snapshot{
+		Version: version,
+		Time:    now.Truncate(time.Second),
+		File:    filepath.Join(h.dir, fmt.Sprintf("%04d_%s.json", version, now.Format(timeFormat))),
+	}
+	err = writeFile(s.File, func(f *os.File) error {
+		return db2go.WriteSchemaJSON(f, schema)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	err = h.appendChangelog(s, latest, changes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, changes, nil
+}
+
+// 追加一条变化记录
+func (h *History) appendChangelog(s, from *Snapshot, changes []*db2go.SchemaChange) error {
+	var str strings.Builder
+	fmt.Fprintf(&str, "## %04d %s\n\n", s.Version, s.Time.Format("2006-01-02 15:04:05 UTC"))
+	if from == nil {
+		fmt.Fprintf(&str, "Initial snapshot `%s`, %d tables.\n\n", filepath.Base(s.File), len(changes))
+	} else {
+		fmt.Fprintf(&str, "`%s` -> `%s`\n\n", filepath.Base(from.File), filepath.Base(s.File))
+		for _, c := range changes {
+			fmt.Fprintf(&str, "- %s\n", c.String())
+		}
+		str.WriteString("\n")
+	}
+	f, err := os.OpenFile(filepath.Join(h.dir, ChangelogFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(str.String())
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeFile(file string, write func(*os.File) error) error {
+	f, err := os.OpenFile(file, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,65 @@
+package history
+
+import (
+	"github.com/qq51529210/db/db2go"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 读取testdata中的结构
+func testSchema(t *testing.T, name string) *db2go.Schema {
+	s, err := db2go.ReadSchemaJSONFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	h, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC)
+	schema := testSchema(t, "schema.json")
+	s, _, err := h.Save(schema, now)
+	if err != nil || s == nil || filepath.Base(s.File) != "0001_20200102T150405Z.json" {
+		t.Fatal(s, err)
+	}
+	// 没有变化
+	s, _, err = h.Save(schema, now.Add(time.Hour))
+	if err != nil || s != nil {
+		t.Fatal(s, err)
+	}
+	// id的类型改成bigint
+	schema = testSchema(t, "schema_bigint.json")
+	s, changes, err := h.Save(schema, now.Add(24*time.Hour))
+	if err != nil || s == nil || s.Version != 2 || len(changes) != 1 {
+		t.Fatal(s, changes, err)
+	}
+	snapshots, err := h.Snapshots()
+	if err != nil || len(snapshots) != 2 {
+		t.Fatal(snapshots, err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, ChangelogFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "## 0001 2020-01-02 15:04:05 UTC\n\n"+
+		"Initial snapshot `0001_20200102T150405Z.json`, 1 tables.\n\n"+
+		"## 0002 2020-01-03 15:04:05 UTC\n\n"+
+		"`0001_20200102T150405Z.json` -> `0002_20200103T150405Z.json`\n\n"+
+		"- column-changed t1.id: int NOT NULL PRIMARY KEY -> bigint NOT NULL PRIMARY KEY\n\n" {
+		t.Fatal(string(data))
+	}
+}
@@ -0,0 +1,192 @@
+package db2go
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	LintPrimaryKey      = "primary-key"       // 表没有主键
+	LintForeignKeyIndex = "foreign-key-index" // 外键列没有索引
+	LintUniqueNullable  = "unique-nullable"   // 唯一索引的列可以为null
+	LintMoneyFloat      = "money-float"       // 金额使用了float/double
+	LintSnakeCase       = "snake-case"        // 表名和列名不是snake_case
+	LintForeignKeyName  = "foreign-key-name"  // 外键列名不是_id结尾
+	LintTableComment    = "table-comment"     // 表没有注释
+	LintColumnComment   = "column-comment"    // 列没有注释
+)
+
+var (
+	lintRule = map[string]func(*Table) []*LintFinding{
+		LintPrimaryKey:      lintPrimaryKey,
+		LintForeignKeyIndex: lintForeignKeyIndex,
+		LintUniqueNullable:  lintUniqueNullable,
+		LintMoneyFloat:      lintMoneyFloat,
+		LintSnakeCase:       lintSnakeCase,
+		LintForeignKeyName:  lintForeignKeyName,
+		LintTableComment:    lintTableComment,
+		LintColumnComment:   lintColumnComment,
+	}
+	lintSnakeCaseRegexp = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+	lintMoneyRegexp     = regexp.MustCompile(`(^|_)(price|amount|money|cost|balance|fee|salary|total)(_|$)`)
+)
+
+// 检查出来的问题
+type LintFinding struct {
+	Rule    string `json:"rule"`             // 规则名称
+	Table   string `json:"table"`            // 表名
+	Column  string `json:"column,omitempty"` // 列名，表级别的问题为空
+	Message string `json:"message"`          // 说明
+}
+
+func (f *LintFinding) String() string {
+	if f.Column == "" {
+		return fmt.Sprintf("[%s] %s: %s", f.Rule, f.Table, f.Message)
+	}
+	return fmt.Sprintf("[%s] %s.%s: %s", f.Rule, f.Table, f.Column, f.Message)
+}
+
+// 所有的规则名称
+func LintRules() []string {
+	var rules []string
+	for k := range lintRule {
+		rules = append(rules, k)
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+// 使用rules检查数据库结构，rules为空表示所有的规则
+func Lint(schema *Schema, rules []string) ([]*LintFinding, error) {
+	if len(rules) < 1 {
+		rules = LintRules()
+	}
+	var funcs []func(*Table) []*LintFinding
+	for _, r := range rules {
+		f, o := lintRule[r]
+		if !o {
+			return nil, fmt.Errorf("unknown lint rule '%s'", r)
+		}
+		funcs = append(funcs, f)
+	}
+	var findings []*LintFinding
+	for _, t := range schema.table {
+		for _, f := range funcs {
+			findings = append(findings, f(t)...)
+		}
+	}
+	return findings, nil
+}
+
+func lintPrimaryKey(t *Table) []*LintFinding {
+	pk, _ := t.PrimaryKeyColumns()
+	if len(pk) > 0 {
+		return nil
+	}
+	return []*LintFinding{{Rule: LintPrimaryKey, Table: t.name, Message: "table has no primary key"}}
+}
+
+func lintForeignKeyIndex(t *Table) []*LintFinding {
+	var fs []*LintFinding
+	for _, c := range t.column {
+		if c.foreignTable == nil || t.HasIndexOn(c) {
+			continue
+		}
+		fs = append(fs, &LintFinding{
+			Rule:    LintForeignKeyIndex,
+			Table:   t.name,
+			Column:  c.name,
+			Message: fmt.Sprintf("foreign key to '%s' has no supporting index", c.foreignTable.table.name),
+		})
+	}
+	return fs
+}
+
+func lintUniqueNullable(t *Table) []*LintFinding {
+	var fs []*LintFinding
+	for _, i := range t.index {
+		if !i.unique || i.primary {
+			continue
+		}
+		for _, c := range i.column {
+			if c.nullable {
+				fs = append(fs, &LintFinding{
+					Rule:    LintUniqueNullable,
+					Table:   t.name,
+					Column:  c.name,
+					Message: fmt.Sprintf("nullable column in unique key '%s' allows duplicate NULL rows", i.name),
+				})
+			}
+		}
+	}
+	return fs
+}
+
+func lintMoneyFloat(t *Table) []*LintFinding {
+	var fs []*LintFinding
+	for _, c := range t.column {
+		typ := strings.ToLower(c._type)
+		if !strings.HasPrefix(typ, "float") && !strings.HasPrefix(typ, "double") && !strings.HasPrefix(typ, "real") {
+			continue
+		}
+		if !lintMoneyRegexp.MatchString(strings.ToLower(c.name)) {
+			continue
+		}
+		fs = append(fs, &LintFinding{
+			Rule:    LintMoneyFloat,
+			Table:   t.name,
+			Column:  c.name,
+			Message: fmt.Sprintf("money stored as '%s', use decimal", c._type),
+		})
+	}
+	return fs
+}
+
+func lintSnakeCase(t *Table) []*LintFinding {
+	var fs []*LintFinding
+	if !lintSnakeCaseRegexp.MatchString(t.name) {
+		fs = append(fs, &LintFinding{Rule: LintSnakeCase, Table: t.name, Message: "table name is not snake_case"})
+	}
+	for _, c := range t.column {
+		if !lintSnakeCaseRegexp.MatchString(c.name) {
+			fs = append(fs, &LintFinding{Rule: LintSnakeCase, Table: t.name, Column: c.name, Message: "column name is not snake_case"})
+		}
+	}
+	return fs
+}
+
+func lintForeignKeyName(t *Table) []*LintFinding {
+	var fs []*LintFinding
+	for _, c := range t.column {
+		if c.foreignTable == nil || strings.HasSuffix(c.name, "_id") {
+			continue
+		}
+		fs = append(fs, &LintFinding{
+			Rule:    LintForeignKeyName,
+			Table:   t.name,
+			Column:  c.name,
+			Message: "foreign key column name should end with '_id'",
+		})
+	}
+	return fs
+}
+
+func lintTableComment(t *Table) []*LintFinding {
+	if strings.TrimSpace(t.comment) != "" {
+		return nil
+	}
+	return []*LintFinding{{Rule: LintTableComment, Table: t.name, Message: "table has no comment"}}
+}
+
+func lintColumnComment(t *Table) []*LintFinding {
+	var fs []*LintFinding
+	for _, c := range t.column {
+		if strings.TrimSpace(c.comment) != "" {
+			continue
+		}
+		fs = append(fs, &LintFinding{Rule: LintColumnComment, Table: t.name, Column: c.name, Message: "column has no comment"})
+	}
+	return fs
+}
@@ -0,0 +1,52 @@
+package db2go
+
+import "testing"
+
+func TestLint(t *testing.T) {
+	s := testSchema()
+	_, err := Lint(s, []string{"not-exists"})
+	if err == nil {
+		t.FailNow()
+	}
+	fs, err := Lint(s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string]bool)
+	for _, f := range fs {
+		found[f.Rule+":"+f.Table+"."+f.Column] = true
+	}
+	for _, k := range []string{
+		LintPrimaryKey + ":T4.",
+		LintSnakeCase + ":T4.",
+		LintForeignKeyIndex + ":t3.t2",
+		LintForeignKeyName + ":t3.t2",
+		LintUniqueNullable + ":t3.t1_id",
+		LintUniqueNullable + ":t3.t2",
+		LintMoneyFloat + ":t2.price",
+		LintTableComment + ":t2.",
+		LintColumnComment + ":t2.name",
+	} {
+		if !found[k] {
+			t.Fatal(k)
+		}
+	}
+	for _, k := range []string{
+		LintPrimaryKey + ":t1.",
+		LintForeignKeyIndex + ":t3.t1_id",
+		LintForeignKeyName + ":t3.t1_id",
+		LintTableComment + ":t1.",
+		LintColumnComment + ":t1.name",
+	} {
+		if found[k] {
+			t.Fatal(k)
+		}
+	}
+	fs, err = Lint(s, []string{LintPrimaryKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs) != 1 || fs[0].Table != "T4" {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,317 @@
+/*
+数据脱敏，按table.column或者列名的模式匹配规则，用于导出和复制数据。
+相同的salt和值总是得到相同的结果，所以外键和用于join的列脱敏后仍然能匹配。
+*/
+package mask
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	MethodHash     = "hash"     // sha256的hex，超过列的长度则截断
+	MethodEmail    = "email"    // 假的邮箱地址
+	MethodNull     = "null"     // NULL，不能为NULL的列则是空值
+	MethodFormat   = "format"   // 保持格式，字母换成字母，数字换成数字，其他字符不变
+	MethodTruncate = "truncate" // 只保留前n个字符
+)
+
+const (
+	defaultTruncate = 1
+	emailDomain     = "example.com"
+	letters         = "abcdefghijklmnopqrstuvwxyz"
+	digits          = "0123456789"
+)
+
+// 脱敏规则
+type Rule struct {
+	Pattern string // table.column，或者列名的模式，比如*email*，使用path.Match匹配，不区分大小写
+	Method  string
+	Length  int // truncate保留的字符数，hash的最大长度，0使用默认值
+}
+
+func (r *Rule) String() string {
+	if r.Length > 0 {
+		return fmt.Sprintf("%s=%s:%d", r.Pattern, r.Method, r.Length)
+	}
+	return r.Pattern + "=" + r.Method
+}
+
+// 匹配表的列
+func (r *Rule) match(table, column string) bool {
+	pattern := strings.ToLower(r.Pattern)
+	name := strings.ToLower(column)
+	if strings.Contains(pattern, ".") {
+		name = strings.ToLower(table) + "." + name
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// 解析规则，格式是pattern=method[:length]，比如"user.email=email"，"*phone*=format"，"name=truncate:2"
+func ParseRule(s string) (*Rule, error) {
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return nil, fmt.Errorf("invalid mask rule '%s', like pattern=method[:length]", s)
+	}
+	r := &Rule{Pattern: strings.TrimSpace(kv[0]), Method: strings.TrimSpace(kv[1])}
+	if i := strings.IndexByte(r.Method, ':'); i >= 0 {
+		n, err := strconv.Atoi(r.Method[i+1:])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid mask rule '%s', length must be a number", s)
+		}
+		r.Length = n
+		r.Method = r.Method[:i]
+	}
+	return r, checkRule(r)
+}
+
+func checkRule(r *Rule) error {
+	switch r.Method {
+	case MethodHash, MethodEmail, MethodNull, MethodFormat, MethodTruncate:
+	default:
+		return fmt.Errorf("unsupported mask method '%s'", r.Method)
+	}
+	if _, err := path.Match(r.Pattern, ""); err != nil {
+		return fmt.Errorf("invalid mask pattern '%s': %v", r.Pattern, err)
+	}
+	return nil
+}
+
+// 脱敏器
+type Masker struct {
+	salt   []byte
+	rules  []*Rule               // 按顺序匹配，table.column优先
+	limits map[*db2go.Column]int // 外键连通的列中最小的长度
+}
+
+// schema用于计算外键连通的列的长度，salt用于hash，不同的salt结果不同
+func NewMasker(schema *db2go.Schema, salt string, rules ...*Rule) (*Masker, error) {
+	m := new(Masker)
+	m.salt = []byte(salt)
+	m.limits = foreignKeyLimits(schema)
+	// 精确的table.column优先
+	for _, r := range rules {
+		err := checkRule(r)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.ContainsAny(r.Pattern, "*?[") && strings.Contains(r.Pattern, ".") {
+			m.rules = append(m.rules, r)
+		}
+	}
+	for _, r := range rules {
+		if strings.ContainsAny(r.Pattern, "*?[") || !strings.Contains(r.Pattern, ".") {
+			m.rules = append(m.rules, r)
+		}
+	}
+	return m, nil
+}
+
+// 列的规则，没有则使用外键引用的列的规则，返回nil表示不需要脱敏
+func (m *Masker) Rule(table *db2go.Table, column *db2go.Column) *Rule {
+	r, _ := m.rule(table, column)
+	return r
+}
+
+// 列的规则，和外键连通的列中最小的长度。
+// 被引用的列和引用它的所有列，包括兄弟列，截断后的值才相同
+func (m *Masker) rule(table *db2go.Table, column *db2go.Column) (*Rule, int) {
+	max := m.limits[column]
+	// 引用的层数有限，避免循环引用
+	for i := 0; i < 32; i++ {
+		for _, r := range m.rules {
+			if r.match(table.Name(), column.Name()) {
+				return r, max
+			}
+		}
+		ft := column.ForeignTable()
+		if ft == nil {
+			return nil, 0
+		}
+		table, column = ft.Table(), ft.Column()
+	}
+	return nil, 0
+}
+
+// 按外键把列分组，每组是引用和被引用的所有列，返回每列所在组的最小长度
+func foreignKeyLimits(schema *db2go.Schema) map[*db2go.Column]int {
+	parent := make(map[*db2go.Column]*db2go.Column)
+	var root func(c *db2go.Column) *db2go.Column
+	root = func(c *db2go.Column) *db2go.Column {
+		p, ok := parent[c]
+		if !ok || p == c {
+			return c
+		}
+		r := root(p)
+		parent[c] = r
+		return r
+	}
+	for _, t := range schema.Tables() {
+		for _, c := range t.Columns() {
+			if ft := c.ForeignTable(); ft != nil {
+				if r1, r2 := root(c), root(ft.Column()); r1 != r2 {
+					parent[r1] = r2
+				}
+			}
+		}
+	}
+	min := make(map[*db2go.Column]int)
+	for _, t := range schema.Tables() {
+		for _, c := range t.Columns() {
+			r := root(c)
+			if n := c.Length(); n > 0 && (min[r] < 1 || n < min[r]) {
+				min[r] = n
+			}
+		}
+	}
+	limits := make(map[*db2go.Column]int)
+	for _, t := range schema.Tables() {
+		for _, c := range t.Columns() {
+			if n := min[root(c)]; n > 0 {
+				limits[c] = n
+			}
+		}
+	}
+	return limits
+}
+
+// 表是否有需要脱敏的列
+func (m *Masker) HasRule(table *db2go.Table) bool {
+	for _, c := range table.Columns() {
+		if m.Rule(table, c) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// 脱敏一个值，v是nil，[]byte，json.Number或者string，返回相同的类型
+func (m *Masker) Mask(table *db2go.Table, column *db2go.Column, v interface{}) interface{} {
+	r, max := m.rule(table, column)
+	if r == nil || v == nil {
+		return v
+	}
+	var s string
+	switch v := v.(type) {
+	case []byte:
+		s = string(v)
+	case json.Number:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		s = fmt.Sprint(v)
+	}
+	if r.Method == MethodNull {
+		if column.IsNullable() {
+			return nil
+		}
+		s = ""
+		if _, ok := v.(json.Number); ok {
+			s = "0"
+		}
+		return sameType(v, s)
+	}
+	// 数字只能保持格式
+	if _, ok := v.(json.Number); ok {
+		return json.Number(m.format(s))
+	}
+	switch r.Method {
+	case MethodHash:
+		s = m.hash(s, r.Length, max)
+	case MethodEmail:
+		s = m.email(s, max)
+	case MethodFormat:
+		s = m.format(s)
+	case MethodTruncate:
+		n := r.Length
+		if n < 1 {
+			n = defaultTruncate
+		}
+		rs := []rune(s)
+		if len(rs) > n {
+			s = string(rs[:n])
+		}
+	}
+	return sameType(v, s)
+}
+
+func sameType(v interface{}, s string) interface{} {
+	switch v.(type) {
+	case []byte:
+		return []byte(s)
+	case json.Number:
+		return json.Number(s)
+	default:
+		return s
+	}
+}
+
+// 由值决定的n个字节
+func (m *Masker) bytes(s string, n int) []byte {
+	var b []byte
+	for i := 0; len(b) < n; i++ {
+		h := hmac.New(sha256.New, m.salt)
+		_, _ = h.Write([]byte{byte(i)})
+		_, _ = h.Write([]byte(s))
+		b = h.Sum(b)
+	}
+	return b[:n]
+}
+
+func (m *Masker) hash(s string, n, max int) string {
+	h := hex.EncodeToString(m.bytes(s, sha256.Size))
+	if n > 0 && n < len(h) {
+		h = h[:n]
+	}
+	if max > 0 && max < len(h) {
+		h = h[:max]
+	}
+	return h
+}
+
+func (m *Masker) email(s string, max int) string {
+	local := hex.EncodeToString(m.bytes(s, 6))
+	email := local + "@" + emailDomain
+	if max > 0 && len(email) > max {
+		// 太短的列，只保留hash
+		if max > len(local) {
+			return email[:max]
+		}
+		return local[:max]
+	}
+	return email
+}
+
+func (m *Masker) format(s string) string {
+	rs := []rune(s)
+	b := m.bytes(s, len(rs))
+	for i, c := range rs {
+		switch {
+		case c >= '0' && c <= '9':
+			// 数字的第一位不为0，避免改变数值的位数
+			if i == 0 || (i == 1 && rs[0] == '-') {
+				rs[i] = rune(digits[1+int(b[i])%9])
+			} else {
+				rs[i] = rune(digits[int(b[i])%10])
+			}
+		case c >= 'a' && c <= 'z':
+			rs[i] = rune(letters[int(b[i])%26])
+		case c >= 'A' && c <= 'Z':
+			rs[i] = rune(letters[int(b[i])%26] - 'a' + 'A')
+		case c > 127:
+			// 非ascii的字符，换成小写字母
+			rs[i] = rune(letters[int(b[i])%26])
+		}
+	}
+	return string(rs)
+}
@@ -0,0 +1,77 @@
+package mask
+
+import (
+	"encoding/json"
+	"github.com/qq51529210/db/db2go"
+	"strings"
+	"testing"
+)
+
+// 读取testdata/schema.json的结构
+func testSchema(t *testing.T) *db2go.Schema {
+	s, err := db2go.ReadSchemaJSONFile("testdata/schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestMasker(t *testing.T) {
+	s := testSchema(t)
+	var rules []*Rule
+	for _, str := range []string{"*phone*=format", "user.email=email", "name=truncate:2", "user.token=hash", "user.id=null", "user.code=hash"} {
+		r, err := ParseRule(str)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, r)
+	}
+	if _, err := ParseRule("name=reverse"); err == nil {
+		t.FailNow()
+	}
+	m, err := NewMasker(s, "salt", rules...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := s.GetTable("user")
+	login := s.GetTable("user_login")
+	// 外键使用被引用的列的规则，结果相同
+	email := m.Mask(user, user.GetColumn("email"), "a@b.com")
+	if email != m.Mask(login, login.GetColumn("user_email"), "a@b.com") || !strings.HasSuffix(email.(string), "@"+emailDomain) {
+		t.Fatal(email)
+	}
+	phone := m.Mask(user, user.GetColumn("phone"), "+86 138-0000").(string)
+	if len(phone) != 12 || phone[0] != '+' || phone[3] != ' ' || phone[7] != '-' || phone == "+86 138-0000" {
+		t.Fatal(phone)
+	}
+	if v := m.Mask(user, user.GetColumn("name"), []byte("alice")); string(v.([]byte)) != "al" {
+		t.Fatal(v)
+	}
+	token := m.Mask(user, user.GetColumn("token"), "secret").(string)
+	if len(token) != 16 {
+		t.Fatal(token)
+	}
+	// 外键的列更长，也使用被引用的列的长度
+	if v := m.Mask(login, login.GetColumn("user_token"), "secret"); v != token {
+		t.Fatal(v)
+	}
+	// 引用的列更短，被引用的列和其他引用它的列也使用最短的长度
+	invite := s.GetTable("user_invite")
+	code := m.Mask(user, user.GetColumn("code"), "secret").(string)
+	if len(code) != 8 || m.Mask(login, login.GetColumn("user_code"), "secret") != code ||
+		m.Mask(invite, invite.GetColumn("code"), "secret") != code {
+		t.Fatal(code)
+	}
+	// 不能为NULL
+	if v := m.Mask(user, user.GetColumn("id"), json.Number("12")); v != json.Number("0") {
+		t.Fatal(v)
+	}
+	if m.Rule(login, login.GetColumn("id")) != nil {
+		t.FailNow()
+	}
+	// salt不同结果不同
+	m2, _ := NewMasker(s, "other", rules...)
+	if m2.Mask(user, user.GetColumn("email"), "a@b.com") == email {
+		t.FailNow()
+	}
+}
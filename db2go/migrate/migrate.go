@@ -0,0 +1,556 @@
+/*
+版本化的数据库迁移，目录下每个版本一个up文件和一个可选的down文件，比如
+
+	0001_create_user.up.sql
+	0001_create_user.down.sql
+
+已经执行的版本和up文件的checksum记录在schema_migrations表，
+执行时使用数据库的锁，避免多个部署同时迁移。sqlite没有命名锁，在schema_migrations_lock表中插入一行，
+迁移的进程异常退出后需要手动删除。
+*/
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultTable       = "schema_migrations" // 默认的版本记录表
+	DefaultLockTimeout = 10                  // 默认等待锁的秒数
+)
+
+var (
+	fileRegexp = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+	// 创建版本记录表
+	createTableFormat = map[string]string{
+		db2go.MYSQL: "create table if not exists %s(" +
+			"version bigint unsigned not null primary key," +
+			"name varchar(255) not null," +
+			"checksum char(64) not null," +
+			"applied_at datetime not null)",
+		db2go.SQLITE: "create table if not exists %s(" +
+			"version integer not null primary key," +
+			"name text not null," +
+			"checksum text not null," +
+			"applied_at datetime not null)",
+	}
+	// 加锁和解锁，参数是锁的名称和超时秒数，没有的数据库使用锁表
+	lockSQL = map[string][2]string{
+		db2go.MYSQL: {"select get_lock(?, ?)", "select release_lock(?)"},
+	}
+)
+
+// 一个版本
+type Migration struct {
+	Version  uint64
+	Name     string
+	Up       string // up文件
+	Down     string // down文件，可能为空
+	Checksum string // up文件的sha256
+}
+
+// 读取up或者down文件，拆分成多条语句
+func (m *Migration) statements(up bool) ([]string, error) {
+	file := m.Up
+	if !up {
+		if m.Down == "" {
+			return nil, fmt.Errorf("version %d has no down file", m.Version)
+		}
+		file = m.Down
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return db2go.SplitStatements(string(data)), nil
+}
+
+// 版本的状态
+type Status struct {
+	Version   uint64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Changed   bool // 执行后up文件被修改了
+	Missing   bool // 已经执行，但是文件不存在
+}
+
+func (s *Status) String() string {
+	state := "pending"
+	if s.Applied {
+		state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+		if s.Changed {
+			state += " (changed)"
+		}
+		if s.Missing {
+			state += " (missing)"
+		}
+	}
+	return fmt.Sprintf("%d %s %s", s.Version, s.Name, state)
+}
+
+// 已经执行的版本
+type applied struct {
+	version   uint64
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// 迁移执行器
+type Migrator struct {
+	db          *sql.DB
+	dbType      string
+	dir         string
+	table       string
+	lockTimeout int
+	snapshot    string
+	output      io.Writer
+	migrations  []*Migration // 按版本排序
+}
+
+// 读取dir下的迁移文件
+func New(dbType string, db *sql.DB, dir string) (*Migrator, error) {
+	if _, ok := createTableFormat[dbType]; !ok {
+		return nil, fmt.Errorf("unsupported db '%s'", dbType)
+	}
+	m := new(Migrator)
+	m.db = db
+	m.dbType = dbType
+	m.dir = dir
+	m.table = DefaultTable
+	m.lockTimeout = DefaultLockTimeout
+	m.output = ioutil.Discard
+	var err error
+	m.migrations, err = ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// 读取目录下的迁移文件，按版本排序
+func ReadDir(dir string) ([]*Migration, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[uint64]*Migration)
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		match := fileRegexp.FindStringSubmatch(info.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", info.Name(), err)
+		}
+		m, ok := versions[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			versions[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("%s: version %d already used by '%s'", info.Name(), version, m.Name)
+		}
+		file := filepath.Join(dir, info.Name())
+		if match[3] == "up" {
+			m.Up = file
+		} else {
+			m.Down = file
+		}
+	}
+	var migrations []*Migration
+	for _, m := range versions {
+		if m.Up == "" {
+			return nil, fmt.Errorf("version %d '%s' has no up file", m.Version, m.Name)
+		}
+		data, err := ioutil.ReadFile(m.Up)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		m.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// 版本记录表的名称，默认是DefaultTable
+func (m *Migrator) SetTable(name string) {
+	m.table = name
+}
+
+// 等待锁的秒数，默认是DefaultLockTimeout
+func (m *Migrator) SetLockTimeout(seconds int) {
+	m.lockTimeout = seconds
+}
+
+// 迁移后把数据库结构以json格式写到file，相对路径是相对于迁移目录，空表示不写
+func (m *Migrator) SetSnapshot(file string) {
+	if file != "" && !filepath.IsAbs(file) {
+		file = filepath.Join(m.dir, file)
+	}
+	m.snapshot = file
+}
+
+// 输出执行的版本，默认不输出
+func (m *Migrator) SetOutput(w io.Writer) {
+	m.output = w
+}
+
+// 目录下的所有版本
+func (m *Migrator) Migrations() []*Migration {
+	return m.migrations
+}
+
+// 所有版本的状态，包括已经执行但文件不存在的版本
+func (m *Migrator) Status() ([]*Status, error) {
+	var status []*Status
+	err := m.withConn(false, func(conn *sql.Conn, done map[uint64]*applied) error {
+		for _, mg := range m.migrations {
+			s := &Status{Version: mg.Version, Name: mg.Name}
+			if a, ok := done[mg.Version]; ok {
+				s.Applied = true
+				s.AppliedAt = a.appliedAt
+				s.Changed = a.checksum != mg.Checksum
+			}
+			status = append(status, s)
+		}
+		for _, a := range done {
+			if m.migration(a.version) == nil {
+				status = append(status, &Status{Version: a.version, Name: a.name, Applied: true, AppliedAt: a.appliedAt, Missing: true})
+			}
+		}
+		return nil
+	})
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Version < status[j].Version
+	})
+	return status, err
+}
+
+// 执行n个未执行的版本，n<1表示全部
+func (m *Migrator) Up(n int) error {
+	return m.withConn(true, func(conn *sql.Conn, done map[uint64]*applied) error {
+		for _, mg := range m.migrations {
+			if _, ok := done[mg.Version]; ok {
+				continue
+			}
+			err := m.apply(conn, mg, true)
+			if err != nil {
+				return err
+			}
+			n--
+			if n == 0 {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// 回滚最近执行的n个版本，n<1表示1个
+func (m *Migrator) Down(n int) error {
+	if n < 1 {
+		n = 1
+	}
+	return m.withConn(true, func(conn *sql.Conn, done map[uint64]*applied) error {
+		for i := len(m.migrations) - 1; i >= 0 && n > 0; i-- {
+			if _, ok := done[m.migrations[i].Version]; !ok {
+				continue
+			}
+			err := m.apply(conn, m.migrations[i], false)
+			if err != nil {
+				return err
+			}
+			n--
+		}
+		return nil
+	})
+}
+
+// 回滚最近执行的版本，然后再执行
+func (m *Migrator) Redo() error {
+	return m.withConn(true, func(conn *sql.Conn, done map[uint64]*applied) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := done[m.migrations[i].Version]; !ok {
+				continue
+			}
+			err := m.apply(conn, m.migrations[i], false)
+			if err != nil {
+				return err
+			}
+			return m.apply(conn, m.migrations[i], true)
+		}
+		return nil
+	})
+}
+
+// 迁移到version，大于version的已执行版本回滚，小于等于的未执行版本执行，0表示全部回滚
+func (m *Migrator) To(version uint64) error {
+	if version > 0 && m.migration(version) == nil {
+		return fmt.Errorf("version %d not found", version)
+	}
+	return m.withConn(true, func(conn *sql.Conn, done map[uint64]*applied) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mg := m.migrations[i]
+			if _, ok := done[mg.Version]; ok && mg.Version > version {
+				err := m.apply(conn, mg, false)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		for _, mg := range m.migrations {
+			if _, ok := done[mg.Version]; !ok && mg.Version <= version {
+				err := m.apply(conn, mg, true)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (m *Migrator) migration(version uint64) *Migration {
+	for _, mg := range m.migrations {
+		if mg.Version == version {
+			return mg
+		}
+	}
+	return nil
+}
+
+// 执行一个版本的up或者down，并更新版本记录表
+func (m *Migrator) apply(conn *sql.Conn, mg *Migration, up bool) error {
+	stmts, err := mg.statements(up)
+	if err != nil {
+		return err
+	}
+	direction := "up"
+	if !up {
+		direction = "down"
+	}
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for i, s := range stmts {
+		_, err = tx.ExecContext(ctx, s)
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("version %d %s: statement %d: %v", mg.Version, direction, i+1, err)
+		}
+	}
+	table := db2go.QuoteName(m.dbType, m.table)
+	if up {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf("insert into %s(version,name,checksum,applied_at) values(%s,%s,%s,%s)", table,
+			db2go.Placeholder(m.dbType, 1), db2go.Placeholder(m.dbType, 2), db2go.Placeholder(m.dbType, 3), db2go.Placeholder(m.dbType, 4)),
+			mg.Version, mg.Name, mg.Checksum, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf("delete from %s where version=%s", table, db2go.Placeholder(m.dbType, 1)), mg.Version)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(m.output, "%s %d %s\n", direction, mg.Version, mg.Name)
+	return nil
+}
+
+// 在同一个连接上，创建版本记录表，读取已执行的版本然后执行fn
+// migrate表示会修改数据库，需要加锁，检查checksum，执行后写结构快照
+func (m *Migrator) withConn(migrate bool, fn func(*sql.Conn, map[uint64]*applied) error) error {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	_, err = conn.ExecContext(ctx, fmt.Sprintf(createTableFormat[m.dbType], db2go.QuoteName(m.dbType, m.table)))
+	if err != nil {
+		return err
+	}
+	if migrate {
+		err = m.lock(conn)
+		if err != nil {
+			return err
+		}
+		defer m.unlock(conn)
+	}
+	done, err := m.applied(conn)
+	if err != nil {
+		return err
+	}
+	if migrate {
+		err = m.verify(done)
+		if err != nil {
+			return err
+		}
+	}
+	err = fn(conn, done)
+	if err != nil || !migrate || m.snapshot == "" {
+		return err
+	}
+	return m.writeSnapshot()
+}
+
+func (m *Migrator) lockName() string {
+	return "db2go_migrate_" + m.table
+}
+
+func (m *Migrator) lock(conn *sql.Conn) error {
+	ctx := context.Background()
+	q, ok := lockSQL[m.dbType]
+	if !ok {
+		return m.lockTable(conn)
+	}
+	var n sql.NullInt64
+	err := conn.QueryRowContext(ctx, q[0], m.lockName(), m.lockTimeout).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n.Int64 != 1 {
+		return fmt.Errorf("another migration is running, lock '%s' timeout", m.lockName())
+	}
+	return nil
+}
+
+func (m *Migrator) unlock(conn *sql.Conn) {
+	ctx := context.Background()
+	if q, ok := lockSQL[m.dbType]; ok {
+		_, _ = conn.ExecContext(ctx, q[1], m.lockName())
+		return
+	}
+	_, _ = conn.ExecContext(ctx, fmt.Sprintf("delete from %s where name=%s",
+		db2go.QuoteName(m.dbType, m.table+"_lock"), db2go.Placeholder(m.dbType, 1)), m.lockName())
+}
+
+// 在锁表中插入一行，已经存在时每100毫秒重试一次，直到超时
+func (m *Migrator) lockTable(conn *sql.Conn) error {
+	ctx := context.Background()
+	table := db2go.QuoteName(m.dbType, m.table+"_lock")
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("create table if not exists %s(name varchar(255) not null primary key)", table))
+	if err != nil {
+		return err
+	}
+	q := fmt.Sprintf("insert into %s(name) select %s where not exists(select 1 from %s where name=%s)",
+		table, db2go.Placeholder(m.dbType, 1), table, db2go.Placeholder(m.dbType, 2))
+	deadline := time.Now().Add(time.Duration(m.lockTimeout) * time.Second)
+	for {
+		res, err := conn.ExecContext(ctx, q, m.lockName(), m.lockName())
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 1 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("another migration is running, lock '%s' in table '%s' timeout", m.lockName(), m.table+"_lock")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// 读取版本记录表
+func (m *Migrator) applied(conn *sql.Conn) (map[uint64]*applied, error) {
+	rows, err := conn.QueryContext(context.Background(),
+		fmt.Sprintf("select version,name,checksum,applied_at from %s", db2go.QuoteName(m.dbType, m.table)))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	done := make(map[uint64]*applied)
+	for rows.Next() {
+		a := new(applied)
+		var appliedAt interface{}
+		err = rows.Scan(&a.version, &a.name, &a.checksum, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		// 连接参数parseTime=true时是time.Time
+		switch v := appliedAt.(type) {
+		case time.Time:
+			a.appliedAt = v
+		case []byte:
+			a.appliedAt, _ = time.Parse("2006-01-02 15:04:05", string(v))
+		case string:
+			a.appliedAt, _ = time.Parse("2006-01-02 15:04:05", v)
+		}
+		done[a.version] = a
+	}
+	return done, rows.Err()
+}
+
+// 检查已执行的版本，文件必须存在且没有被修改
+func (m *Migrator) verify(done map[uint64]*applied) error {
+	var versions []uint64
+	for v := range done {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] < versions[j]
+	})
+	for _, v := range versions {
+		mg := m.migration(v)
+		if mg == nil {
+			return fmt.Errorf("version %d '%s' was applied but its file is missing", v, done[v].name)
+		}
+		if mg.Checksum != done[v].checksum {
+			return fmt.Errorf("version %d '%s' was modified after it was applied, checksum %s, applied %s",
+				v, mg.Name, mg.Checksum, done[v].checksum)
+		}
+	}
+	return nil
+}
+
+// 把数据库结构写到快照文件
+func (m *Migrator) writeSnapshot() error {
+	schema, err := db2go.ReadSchemaDB(m.dbType, m.db)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(m.snapshot, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	err = db2go.WriteSchemaJSON(f, schema)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,247 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/qq51529210/db/db2go"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	write := func(name, data string) {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("0002_add_name.up.sql", "alter table user add name varchar(32);")
+	write("0001_create_user.up.sql", "create table user(id int);")
+	write("0001_create_user.down.sql", "drop table user;")
+	write("README.md", "")
+	migrations, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 ||
+		migrations[0].Version != 1 || migrations[0].Name != "create_user" || migrations[0].Down == "" ||
+		migrations[1].Version != 2 || migrations[1].Down != "" || len(migrations[1].Checksum) != 64 {
+		t.Fatal(migrations)
+	}
+	_, err = migrations[1].statements(false)
+	if err == nil {
+		t.FailNow()
+	}
+	// 版本重复
+	write("02_other.up.sql", "")
+	_, err = ReadDir(dir)
+	if err == nil {
+		t.FailNow()
+	}
+}
+
+// sqlite数据库文件和迁移目录，3个版本
+func testMigrator(t *testing.T) (*Migrator, *sql.DB, string) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("0001_create_user.up.sql", "create table user(id integer primary key, name text);")
+	write("0001_create_user.down.sql", "drop table user;")
+	write("0002_create_log.up.sql", `create table log(id integer);
+create trigger user_log after insert on user
+begin
+  insert into log values(new.id);
+end;`)
+	write("0002_create_log.down.sql", "drop trigger user_log; drop table log;")
+	write("0003_add_email.up.sql", "alter table user add email text;")
+	write("0003_add_email.down.sql", "alter table user drop column email;")
+	db, err := sql.Open(db2go.SQLITE, filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	m, err := New(db2go.SQLITE, db, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, db, dir
+}
+
+// 已执行的版本，比如"1,2"
+func testApplied(t *testing.T, m *Migrator) string {
+	status, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []string
+	for _, s := range status {
+		if s.Applied {
+			versions = append(versions, strconv.FormatUint(s.Version, 10))
+		}
+	}
+	return strings.Join(versions, ",")
+}
+
+func TestMigrate(t *testing.T) {
+	m, db, _ := testMigrator(t)
+	var out bytes.Buffer
+	m.SetOutput(&out)
+	err := m.Up(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := testApplied(t, m); v != "1,2" {
+		t.Fatal(v)
+	}
+	// 触发器的语句体没有被拆分
+	_, err = db.Exec("insert into user(id,name) values(1,'a')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	err = db.QueryRow("select count(*) from log").Scan(&n)
+	if err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+	err = m.Up(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := testApplied(t, m); v != "1,2,3" {
+		t.Fatal(v)
+	}
+	err = m.Down(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := testApplied(t, m); v != "1" {
+		t.Fatal(v)
+	}
+	out.Reset()
+	err = m.Redo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "down 1 create_user\nup 1 create_user\n" {
+		t.Fatal(out.String())
+	}
+	err = m.To(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := testApplied(t, m); v != "1,2,3" {
+		t.Fatal(v)
+	}
+	err = m.To(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := testApplied(t, m); v != "1" {
+		t.Fatal(v)
+	}
+	if err = m.To(9); err == nil {
+		t.FailNow()
+	}
+	err = m.To(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := testApplied(t, m); v != "" {
+		t.Fatal(v)
+	}
+}
+
+func TestMigrateVerify(t *testing.T) {
+	m, db, dir := testMigrator(t)
+	err := m.Up(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 执行后修改了up文件
+	err = ioutil.WriteFile(filepath.Join(dir, "0002_create_log.up.sql"), []byte("create table log(id int);"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err = New(db2go.SQLITE, db, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := m.Status()
+	if err != nil || !status[1].Changed {
+		t.Fatal(status, err)
+	}
+	err = m.Down(1)
+	if err == nil || !strings.Contains(err.Error(), "version 2 'create_log' was modified") {
+		t.Fatal(err)
+	}
+	// 执行后删除了文件
+	for _, name := range []string{"0002_create_log.up.sql", "0002_create_log.down.sql"} {
+		err = os.Remove(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err = New(db2go.SQLITE, db, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Up(0)
+	if err == nil || !strings.Contains(err.Error(), "file is missing") {
+		t.Fatal(err)
+	}
+	if v := testApplied(t, m); v != "1,2,3" {
+		t.Fatal(v)
+	}
+}
+
+func TestMigrateLock(t *testing.T) {
+	m, db, _ := testMigrator(t)
+	m.SetLockTimeout(0)
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	err = m.lock(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Up(0)
+	if err == nil || !strings.Contains(err.Error(), "another migration is running") {
+		t.Fatal(err)
+	}
+	if v := testApplied(t, m); v != "" {
+		t.Fatal(v)
+	}
+	m.unlock(conn)
+	err = m.Up(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 执行后解锁
+	err = m.lock(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,153 @@
+package db2go
+
+import "strings"
+
+// 存储过程，函数，触发器和事件的语句体中可以有;
+var compoundStatements = map[string]bool{
+	"trigger": true, "procedure": true, "function": true, "event": true,
+}
+
+// 按;拆分多条sql，忽略字符串，标识符和注释中的;，去掉只有注释的语句。
+// 支持mysql客户端的DELIMITER，create trigger等语句的BEGIN...END中的;不拆分，
+// 支持postgres的$tag$...$tag$
+func SplitStatements(s string) []string {
+	var stmts []string
+	var stmt strings.Builder
+	hasCode := false
+	delimiter := ";"
+	// 语句的第一个词，是否create trigger等，BEGIN...END的层数
+	first := ""
+	compound := false
+	depth := 0
+	add := func() {
+		if hasCode {
+			stmts = append(stmts, strings.TrimSpace(stmt.String()))
+		}
+		stmt.Reset()
+		hasCode = false
+		first = ""
+		compound = false
+		depth = 0
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case strings.HasPrefix(s[i:], delimiter) && (depth < 1 || delimiter != ";"):
+			add()
+			i += len(delimiter) - 1
+		case c == '\'' || c == '"' || c == '`':
+			j := i + 1
+			for j < len(s) && s[j] != c {
+				if s[j] == '\\' && c != '`' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				j = len(s) - 1
+			}
+			stmt.WriteString(s[i : j+1])
+			hasCode = true
+			i = j
+		case c == '$' && (i == 0 || !isWordByte(s[i-1])) && dollarQuoteTag(s[i:]) != "":
+			tag := dollarQuoteTag(s[i:])
+			j := strings.Index(s[i+len(tag):], tag)
+			if j < 0 {
+				j = len(s) - i
+			} else {
+				j += len(tag) * 2
+			}
+			stmt.WriteString(s[i : i+j])
+			hasCode = true
+			i += j - 1
+		case c == '#' || (c == '-' && strings.HasPrefix(s[i:], "-- ")) || (c == '-' && strings.HasPrefix(s[i:], "--\n")):
+			// 单行注释
+			j := strings.IndexByte(s[i:], '\n')
+			if j < 0 {
+				i = len(s)
+				break
+			}
+			stmt.WriteByte('\n')
+			i += j
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			j := strings.Index(s[i+2:], "*/")
+			if j < 0 {
+				i = len(s)
+				break
+			}
+			stmt.WriteByte(' ')
+			i += j + 3
+		case isWordByte(c) && (i == 0 || !isWordByte(s[i-1])):
+			j := i + 1
+			for j < len(s) && isWordByte(s[j]) {
+				j++
+			}
+			word := strings.ToLower(s[i:j])
+			if !hasCode && word == "delimiter" {
+				// DELIMITER到行尾是新的分隔符
+				k := strings.IndexByte(s[j:], '\n')
+				if k < 0 {
+					k = len(s) - j
+				}
+				if d := strings.TrimSpace(s[j : j+k]); d != "" {
+					delimiter = d
+				}
+				stmt.Reset()
+				i = j + k - 1
+				break
+			}
+			stmt.WriteString(s[i:j])
+			hasCode = true
+			i = j - 1
+			switch {
+			case first == "":
+				first = word
+			case first == "create" && depth < 1 && compoundStatements[word]:
+				compound = true
+			case compound && (word == "begin" || word == "case"):
+				depth++
+			case compound && word == "end" && depth > 0:
+				// END IF，END LOOP等没有对应的BEGIN
+				switch nextWord(s[j:]) {
+				case "if", "loop", "while", "repeat":
+				default:
+					depth--
+				}
+			}
+		default:
+			stmt.WriteByte(c)
+			if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+				hasCode = true
+			}
+		}
+	}
+	add()
+	return stmts
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// s开始的空白后面的词，小写
+func nextWord(s string) string {
+	s = strings.TrimLeft(s, " \t\r\n")
+	i := 0
+	for i < len(s) && isWordByte(s[i]) {
+		i++
+	}
+	return strings.ToLower(s[:i])
+}
+
+// postgres的$tag$，s不是以它开始返回空
+func dollarQuoteTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '$' {
+			return s[:i+1]
+		}
+		if !isWordByte(s[i]) || (i == 1 && s[i] >= '0' && s[i] <= '9') {
+			return ""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package db2go
+
+import "testing"
+
+func TestSplitStatements(t *testing.T) {
+	stmts := SplitStatements(`-- create user
+create table user(id int, name varchar(32) default 'a;b'); # comment;
+/* block; comment */
+insert into user values(1, "x\";y");
+-- only comment;
+`)
+	if len(stmts) != 2 ||
+		stmts[0] != "create table user(id int, name varchar(32) default 'a;b')" ||
+		stmts[1] != `insert into user values(1, "x\";y")` {
+		t.Fatalf("%q", stmts)
+	}
+}
+
+func TestSplitStatementsCompound(t *testing.T) {
+	// mysql客户端的DELIMITER
+	stmts := SplitStatements(`DELIMITER $$
+create procedure p() begin select 1; select 2; end$$
+DELIMITER ;
+select 3;`)
+	if len(stmts) != 2 || stmts[0] != "create procedure p() begin select 1; select 2; end" || stmts[1] != "select 3" {
+		t.Fatalf("%q", stmts)
+	}
+	// BEGIN...END
+	stmts = SplitStatements(`create trigger t after insert on user for each row
+begin
+  if new.id > 0 then
+    insert into log values(new.id);
+  end if;
+  set @a = case when new.id > 1 then 1 else 0 end;
+end;
+create table t(id int);`)
+	if len(stmts) != 2 || stmts[1] != "create table t(id int)" {
+		t.Fatalf("%q", stmts)
+	}
+	// postgres的$$
+	stmts = SplitStatements(`create function f() returns int as $$ begin return 1; end; $$ language plpgsql;
+select f();`)
+	if len(stmts) != 2 || stmts[0] != "create function f() returns int as $$ begin return 1; end; $$ language plpgsql" {
+		t.Fatalf("%q", stmts)
+	}
+	// 不是语句体的begin
+	stmts = SplitStatements(`begin; select $1; commit;`)
+	if len(stmts) != 3 {
+		t.Fatalf("%q", stmts)
+	}
+}
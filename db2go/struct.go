@@ -0,0 +1,487 @@
+package db2go
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/*
+从go的结构体生成结构，列的类型使用mysql的，生成其他数据库的DDL时再转换。
+字段使用db标签，没有则使用json标签，名称为空使用字段名的snake_case，"-"表示忽略，比如
+	type User struct {
+		ID    int64  `db:"id,pk,auto"`
+		Name  string `db:"name,size:32,unique"`
+		Email *string `json:"email"`
+	}
+选项有
+	pk          主键
+	auto        自增
+	null        可以为NULL，指针和sql.NullX类型默认可以为NULL
+	unique      唯一索引
+	index       普通索引
+	size:n      字符串和[]byte的长度
+	type:t      直接指定列的类型，比如type:decimal(10,2)
+	default:v   默认值
+	fk:t.c      外键，引用表t的列c
+	comment:s   注释
+表名是结构体名称的snake_case，有TableName() string方法则使用它的返回值。
+*/
+
+const (
+	defaultStringSize = 255
+)
+
+// 结构体的一个字段
+type goField struct {
+	name   string // 字段名
+	goType string // 类型，比如"*int64"，"sql.NullString"，"time.Time"
+	db     string // db标签
+	json   string // json标签
+}
+
+// 一个结构体
+type goStruct struct {
+	name   string
+	table  string // TableName()的返回值
+	fields []*goField
+}
+
+// 解析go源码，有db或者json标签并且没有被嵌入的结构体是一个表，src为nil则读取文件
+func ReadSchemaGo(filename string, src interface{}) (*Schema, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	structs := make(map[string]*ast.StructType)
+	var names []string
+	tableNames := make(map[string]string)
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					structs[ts.Name.Name] = st
+					names = append(names, ts.Name.Name)
+				}
+			}
+		case *ast.FuncDecl:
+			// func (User) TableName() string { return "user" }
+			if d.Recv == nil || len(d.Recv.List) != 1 || d.Name.Name != "TableName" || d.Body == nil || len(d.Body.List) != 1 {
+				continue
+			}
+			ret, ok := d.Body.List[0].(*ast.ReturnStmt)
+			if !ok || len(ret.Results) != 1 {
+				continue
+			}
+			lit, ok := ret.Results[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				continue
+			}
+			recv := d.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			if id, ok := recv.(*ast.Ident); ok {
+				tableNames[id.Name], _ = strconv.Unquote(lit.Value)
+			}
+		}
+	}
+	// 被嵌入的结构体不是表
+	embedded := make(map[string]bool)
+	for _, st := range structs {
+		for _, field := range st.Fields.List {
+			if len(field.Names) < 1 {
+				embedded[strings.TrimPrefix(astTypeString(field.Type), "*")] = true
+			}
+		}
+	}
+	var gs []*goStruct
+	for _, name := range names {
+		if embedded[name] {
+			continue
+		}
+		s := &goStruct{name: name, table: tableNames[name]}
+		s.fields = astFields(structs, structs[name], 0)
+		// 没有标签的结构体不是表
+		tagged := false
+		for _, f := range s.fields {
+			if f.db != "" || f.json != "" {
+				tagged = true
+				break
+			}
+		}
+		if tagged {
+			gs = append(gs, s)
+		}
+	}
+	return goStructsToSchema(strings.TrimSuffix(filepath.Base(filename), ".go"), gs)
+}
+
+// 结构体的字段，展开嵌入的结构体
+func astFields(structs map[string]*ast.StructType, st *ast.StructType, depth int) []*goField {
+	var fields []*goField
+	for _, field := range st.Fields.List {
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			s, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(s)
+		}
+		typ := astTypeString(field.Type)
+		if len(field.Names) < 1 {
+			// 嵌入同一个文件中的结构体
+			if embed, ok := structs[strings.TrimPrefix(typ, "*")]; ok && depth < 8 && tag.Get("db") == "" {
+				fields = append(fields, astFields(structs, embed, depth+1)...)
+			}
+			continue
+		}
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+			fields = append(fields, &goField{name: name.Name, goType: typ, db: tag.Get("db"), json: tag.Get("json")})
+		}
+	}
+	return fields
+}
+
+func astTypeString(e ast.Expr) string {
+	switch t := e.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return "*" + astTypeString(t.X)
+	case *ast.SelectorExpr:
+		return astTypeString(t.X) + "." + t.Sel.Name
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "[]" + astTypeString(t.Elt)
+		}
+		return "array"
+	default:
+		return fmt.Sprintf("%T", e)
+	}
+}
+
+// 使用反射读取结构体，values是结构体或者结构体的指针
+func ReadSchemaStruct(name string, values ...interface{}) (*Schema, error) {
+	var gs []*goStruct
+	for _, v := range values {
+		t := reflect.TypeOf(v)
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%T is not a struct", v)
+		}
+		s := &goStruct{name: t.Name(), fields: reflectFields(t, 0)}
+		if tn, ok := v.(interface{ TableName() string }); ok {
+			s.table = tn.TableName()
+		}
+		gs = append(gs, s)
+	}
+	return goStructsToSchema(name, gs)
+}
+
+func reflectFields(t reflect.Type, depth int) []*goField {
+	var fields []*goField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Tag.Get("db") == "" && depth < 8 {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft.String() != "time.Time" {
+				fields = append(fields, reflectFields(ft, depth+1)...)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		typ := f.Type.String()
+		if typ == "[]uint8" {
+			typ = "[]byte"
+		}
+		fields = append(fields, &goField{name: f.Name, goType: typ, db: f.Tag.Get("db"), json: f.Tag.Get("json")})
+	}
+	return fields
+}
+
+// 驼峰转换成snake_case，比如UserID是user_id
+func snakeCase(s string) string {
+	rs := []rune(s)
+	var str strings.Builder
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(rs[i-1]) || (i+1 < len(rs) && unicode.IsLower(rs[i+1]))) {
+				str.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		str.WriteRune(r)
+	}
+	return str.String()
+}
+
+// 列的选项
+type goColumnOption struct {
+	name    string
+	pk      bool
+	auto    bool
+	null    bool
+	unique  bool
+	index   bool
+	size    int
+	_type   string
+	def     string
+	fk      string
+	comment string
+}
+
+// 解析db标签，没有则使用json标签，返回nil表示忽略
+func parseGoTag(f *goField) (*goColumnOption, error) {
+	tag := f.db
+	if tag == "" {
+		tag = f.json
+	}
+	if tag == "-" {
+		return nil, nil
+	}
+	parts := splitGoTag(tag)
+	o := &goColumnOption{name: strings.TrimSpace(parts[0])}
+	if o.name == "" {
+		o.name = snakeCase(f.name)
+	}
+	// json的选项不是列的选项
+	if f.db == "" {
+		return o, nil
+	}
+	for _, p := range parts[1:] {
+		p = strings.TrimSpace(p)
+		kv := strings.SplitN(p, ":", 2)
+		switch kv[0] {
+		case "pk":
+			o.pk = true
+		case "auto":
+			o.auto = true
+		case "null":
+			o.null = true
+		case "unique":
+			o.unique = true
+		case "index":
+			o.index = true
+		case "size", "type", "default", "fk", "comment":
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("field '%s': option '%s' needs a value", f.name, kv[0])
+			}
+			switch kv[0] {
+			case "size":
+				n, err := strconv.Atoi(kv[1])
+				if err != nil || n < 1 {
+					return nil, fmt.Errorf("field '%s': invalid size '%s'", f.name, kv[1])
+				}
+				o.size = n
+			case "type":
+				o._type = kv[1]
+			case "default":
+				o.def = kv[1]
+			case "fk":
+				o.fk = kv[1]
+			case "comment":
+				o.comment = kv[1]
+			}
+		case "", "omitempty":
+		default:
+			return nil, fmt.Errorf("field '%s': unknown option '%s'", f.name, p)
+		}
+	}
+	return o, nil
+}
+
+// 按逗号分割，括号里的逗号不分割，比如type:decimal(10,2)
+func splitGoTag(tag string) []string {
+	var parts []string
+	depth, begin := 0, 0
+	for i := 0; i < len(tag); i++ {
+		switch tag[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth < 1 {
+				parts = append(parts, tag[begin:i])
+				begin = i + 1
+			}
+		}
+	}
+	return append(parts, tag[begin:])
+}
+
+// go类型对应的mysql类型，以及是否可以为NULL
+func goTypeToColumnType(goType string, size int) (string, bool, error) {
+	null := false
+	if strings.HasPrefix(goType, "*") {
+		null = true
+		goType = goType[1:]
+	}
+	switch goType {
+	case "sql.NullString":
+		null, goType = true, "string"
+	case "sql.NullInt64":
+		null, goType = true, "int64"
+	case "sql.NullInt32":
+		null, goType = true, "int32"
+	case "sql.NullInt16":
+		null, goType = true, "int16"
+	case "sql.NullByte":
+		null, goType = true, "uint8"
+	case "sql.NullFloat64":
+		null, goType = true, "float64"
+	case "sql.NullBool":
+		null, goType = true, "bool"
+	case "sql.NullTime":
+		null, goType = true, "time.Time"
+	}
+	switch goType {
+	case "int8":
+		return "tinyint", null, nil
+	case "int16":
+		return "smallint", null, nil
+	case "int32":
+		return "int", null, nil
+	case "int", "int64":
+		// int是64位的
+		return "bigint", null, nil
+	case "uint8", "byte":
+		return "tinyint unsigned", null, nil
+	case "uint16":
+		return "smallint unsigned", null, nil
+	case "uint32":
+		return "int unsigned", null, nil
+	case "uint", "uint64":
+		return "bigint unsigned", null, nil
+	case "float32":
+		return "float", null, nil
+	case "float64":
+		return "double", null, nil
+	case "bool":
+		return "tinyint(1)", null, nil
+	case "string":
+		if size < 1 {
+			size = defaultStringSize
+		}
+		if size > 65535/4 {
+			return "text", null, nil
+		}
+		return "varchar(" + strconv.Itoa(size) + ")", null, nil
+	case "[]byte":
+		if size > 0 && size <= 65535 {
+			return "varbinary(" + strconv.Itoa(size) + ")", true, nil
+		}
+		return "blob", true, nil
+	case "time.Time":
+		return "datetime", null, nil
+	default:
+		return "", false, fmt.Errorf("unsupported go type '%s'", goType)
+	}
+}
+
+// 生成结构
+func goStructsToSchema(name string, structs []*goStruct) (*Schema, error) {
+	s := &Schema{dbType: MYSQL, name: name}
+	fks := make(map[*Column]string)
+	for _, gs := range structs {
+		t := &Table{name: gs.table, stats: new(TableStats)}
+		if t.name == "" {
+			t.name = snakeCase(gs.name)
+		}
+		if s.GetTable(t.name) != nil {
+			return nil, fmt.Errorf("struct '%s': table '%s' already defined", gs.name, t.name)
+		}
+		var pk []*Column
+		for _, f := range gs.fields {
+			o, err := parseGoTag(f)
+			if err != nil {
+				return nil, fmt.Errorf("struct '%s': %v", gs.name, err)
+			}
+			if o == nil {
+				continue
+			}
+			if t.GetColumn(o.name) != nil {
+				return nil, fmt.Errorf("struct '%s': column '%s' already defined", gs.name, o.name)
+			}
+			typ, null, err := goTypeToColumnType(f.goType, o.size)
+			if err != nil {
+				// 指定了类型则不需要转换
+				if o._type == "" {
+					return nil, fmt.Errorf("struct '%s' field '%s': %v", gs.name, f.name, err)
+				}
+			}
+			if o._type != "" {
+				typ = o._type
+			}
+			c := &Column{
+				dbType:        MYSQL,
+				name:          o.name,
+				_type:         typ,
+				primaryKey:    o.pk,
+				autoIncrement: o.auto,
+				unique:        o.unique,
+				nullable:      (null || o.null) && !o.pk,
+				defaultValue:  o.def,
+				comment:       o.comment,
+			}
+			t.column = append(t.column, c)
+			if o.pk {
+				pk = append(pk, c)
+			}
+			// 索引的类型和mysql读取的一样是BTREE
+			if o.unique {
+				t.index = append(t.index, &Index{name: t.name + "_" + c.name + "_uindex", _type: "BTREE", unique: true, column: []*Column{c}})
+			} else if o.index || o.fk != "" {
+				t.index = append(t.index, &Index{name: t.name + "_" + c.name + "_index", _type: "BTREE", column: []*Column{c}})
+			}
+			if o.fk != "" {
+				fks[c] = o.fk
+			}
+		}
+		if len(t.column) < 1 {
+			return nil, fmt.Errorf("struct '%s' has no columns", gs.name)
+		}
+		if len(pk) > 0 {
+			t.index = append([]*Index{{name: "PRIMARY", _type: "BTREE", unique: true, primary: true, column: pk}}, t.index...)
+		}
+		s.table = append(s.table, t)
+	}
+	// 外键
+	for c, fk := range fks {
+		i := strings.LastIndexByte(fk, '.')
+		if i < 1 {
+			return nil, fmt.Errorf("column '%s': invalid fk '%s', like table.column", c.name, fk)
+		}
+		ref := s.GetTable(fk[:i])
+		if ref == nil {
+			return nil, fmt.Errorf("column '%s': fk table '%s' not found", c.name, fk[:i])
+		}
+		refColumn := ref.GetColumn(fk[i+1:])
+		if refColumn == nil {
+			return nil, fmt.Errorf("column '%s': fk column '%s' not found", c.name, fk)
+		}
+		c.foreignTable = &ForeignTable{table: ref, column: refColumn}
+	}
+	return s, nil
+}
@@ -0,0 +1,153 @@
+package db2go
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testStructSource = `package model
+
+type Base struct {
+	ID        int64     ` + "`db:\"id,pk,auto\"`" + `
+	CreatedAt time.Time ` + "`db:\"created_at\"`" + `
+}
+
+type User struct {
+	Base
+	Name  string  ` + "`db:\"name,size:32,unique\"`" + `
+	Email *string ` + "`json:\"email,omitempty\"`" + `
+	Salt  []byte  ` + "`db:\"salt,size:16\"`" + `
+	cache string
+	Temp  string  ` + "`db:\"-\"`" + `
+}
+
+type UserOrder struct {
+	ID     int64   ` + "`db:\"id,pk,auto\"`" + `
+	UserID int64   ` + "`db:\"user_id,fk:user.id\"`" + `
+	Price  float64 ` + "`db:\"price,type:decimal(10,2),default:0\"`" + `
+	Count  int
+	Flags  uint
+}
+
+func (UserOrder) TableName() string { return "orders" }
+
+type helper struct {
+	n int
+}
+`
+
+func testStructSchema(t *testing.T, s *Schema) {
+	if len(s.Tables()) != 2 {
+		t.Fatal(len(s.Tables()))
+	}
+	user := s.GetTable("user")
+	if user == nil {
+		t.FailNow()
+	}
+	var names []string
+	for _, c := range user.Columns() {
+		names = append(names, c.Name())
+	}
+	if strings.Join(names, ",") != "id,created_at,name,email,salt" {
+		t.Fatal(names)
+	}
+	id := user.GetColumn("id")
+	if !id.IsPrimaryKey() || !id.IsAutoIncrement() || id.Type() != "bigint" || id.IsNullable() {
+		t.FailNow()
+	}
+	if c := user.GetColumn("name"); c.Type() != "varchar(32)" || !c.IsUnique() {
+		t.Fatal(c.Type())
+	}
+	if c := user.GetColumn("email"); c.Type() != "varchar(255)" || !c.IsNullable() {
+		t.Fatal(c.Type())
+	}
+	if c := user.GetColumn("salt"); c.Type() != "varbinary(16)" {
+		t.Fatal(c.Type())
+	}
+	orders := s.GetTable("orders")
+	if orders == nil {
+		t.FailNow()
+	}
+	c := orders.GetColumn("user_id")
+	if c.ForeignTable() == nil || c.ForeignTable().Table() != user || c.ForeignTable().Column() != id {
+		t.FailNow()
+	}
+	if c := orders.GetColumn("price"); c.Type() != "decimal(10,2)" || c.DefaultValue() != "0" {
+		t.Fatal(c.Type())
+	}
+	// int和uint是64位的
+	if c := orders.GetColumn("count"); c.Type() != "bigint" {
+		t.Fatal(c.Type())
+	}
+	if c := orders.GetColumn("flags"); c.Type() != "bigint unsigned" {
+		t.Fatal(c.Type())
+	}
+	ddl, err := TableDDL(MYSQL, orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(ddl, "FOREIGN KEY") {
+		t.Fatal(ddl)
+	}
+}
+
+func TestReadSchemaGo(t *testing.T) {
+	s, err := ReadSchemaGo("model.go", testStructSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStructSchema(t, s)
+	_, err = ReadSchemaGo("model.go", "package model\ntype A struct {\n\tB int `db:\"b,fk:c.d\"`\n}\n")
+	if err == nil {
+		t.FailNow()
+	}
+}
+
+type testBase struct {
+	ID        int64     `db:"id,pk,auto"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type testUser struct {
+	testBase
+	Name  string  `db:"name,size:32,unique"`
+	Email *string `json:"email,omitempty"`
+	Salt  []byte  `db:"salt,size:16"`
+	cache string
+	Temp  string `db:"-"`
+}
+
+func (testUser) TableName() string { return "user" }
+
+type testUserOrder struct {
+	ID     int64           `db:"id,pk,auto"`
+	UserID int64           `db:"user_id,fk:user.id"`
+	Price  sql.NullFloat64 `db:"price,type:decimal(10,2),default:0"`
+	Count  int
+	Flags  uint
+}
+
+func (testUserOrder) TableName() string { return "orders" }
+
+func TestReadSchemaStruct(t *testing.T) {
+	s, err := ReadSchemaStruct("model", new(testUser), testUserOrder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStructSchema(t, s)
+}
+
+func TestSnakeCase(t *testing.T) {
+	for k, v := range map[string]string{
+		"UserID":    "user_id",
+		"HTTPCode":  "http_code",
+		"UserOrder": "user_order",
+		"id":        "id",
+	} {
+		if s := snakeCase(k); s != v {
+			t.Fatal(k, s)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package gen
+
+import (
+	"database/sql"
+	"github.com/qq51529210/db/db2go"
+	"sort"
+	"sync"
+)
+
+var (
+	backendLock sync.RWMutex
+	backends    = make(map[string]Backend)
+)
+
+// 结果集的一列
+type Column struct {
+	Name         string // 列名
+	DatabaseType string // 数据库的类型，比如"INT"
+	Nullable     bool   // 是否可以为NULL，不知道时为true
+	GoType       string // 由Backend.GoType得到
+}
+
+// sql的语法，解析sql时使用
+type Syntax int
+
+const (
+	// 标准sql，比如sqlite
+	StandardSyntax Syntax = iota
+	// mysql：字符串中的\是转义符，#是注释，执行/*!...*/中的sql
+	MySQLSyntax
+	// postgres：E'...'和$tag$...$tag$字符串，注释可以嵌套
+	PostgresSyntax
+)
+
+// 一种数据库的代码生成，在init()中用Register注册
+type Backend interface {
+	// 默认的驱动包，比如"github.com/go-sql-driver/mysql"
+	DriverPkg() string
+	// 默认的驱动名称，也就是sql.Open的第一个参数，比如"mysql"
+	DriverName() string
+	// 打开测试sql的连接池，dbUrl是db2go.DSN.String()
+	Open(dbUrl string) (*sql.DB, error)
+	// 第index个参数的占位符，index从1开始，比如"?"，"$1"
+	Placeholder(index int) string
+	// 名称相同的参数是否使用同一个占位符
+	ReuseParam() bool
+	// sql的语法
+	Syntax() Syntax
+	// 测试query时参数的值，typ是{name:type}中的type
+	TestValue(typ string) interface{}
+	// 测试query的结果集的列，rows还没有调用Next
+	Columns(rows *sql.Rows) ([]*Column, error)
+	// 数据库的类型对应的go类型，databaseType是Columns返回的类型，
+	// 返回空表示不能推断，需要在types中指定
+	GoType(databaseType string) string
+	// 表结构中声明的类型对应的go类型，比如"int(11) unsigned"，"bigint"，离线生成时使用
+	DeclaredGoType(columnType string) string
+	// 生成IsUniqueKeyError等辅助函数的代码，driverPkg不支持时返回空，
+	// 不为空时生成的代码会导入driverPkg，而不是只注册驱动
+	ErrorHelper(driverPkg string) string
+}
+
+// 可以读取数据库结构的Backend，生成CheckSchema，可以分析执行计划
+type SchemaBackend interface {
+	Backend
+	// db2go的数据库类型，比如db2go.MYSQL
+	DBType() string
+}
+
+// 弱类型的数据库，列中的值可以和声明的类型不同，生成的代码用ScanFunc包装Scan的参数
+type ScanBackend interface {
+	Backend
+	// db2go中的函数，比如"db2go.SQLiteScan"，参数是int64，float64，bool，string，[]byte
+	// 和对应的sql.NullXxx的指针，返回sql.Scanner
+	ScanFunc() string
+}
+
+// 注册，dbType是db2go的数据库类型，也就是dbUrl的scheme
+func Register(dbType string, b Backend) {
+	backendLock.Lock()
+	backends[dbType] = b
+	backendLock.Unlock()
+}
+
+// 没有注册返回nil
+func GetBackend(dbType string) Backend {
+	backendLock.RLock()
+	defer backendLock.RUnlock()
+	return backends[dbType]
+}
+
+// 已注册的数据库类型
+func Backends() []string {
+	backendLock.RLock()
+	defer backendLock.RUnlock()
+	var names []string
+	for k := range backends {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 读取数据库结构
+func readSchema(b Backend, db *sql.DB) (*db2go.Schema, error) {
+	sb, ok := b.(SchemaBackend)
+	if !ok {
+		return nil, nil
+	}
+	return db2go.ReadSchemaDB(sb.DBType(), db)
+}
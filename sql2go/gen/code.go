@@ -0,0 +1,572 @@
+package gen
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	errEmptyFunctionName = errors.New("empty function name")
+	errEmptySQL          = errors.New("empty sql")
+	errNotSelect         = errors.New("query must be a select")
+	errNoResultColumn    = errors.New("query has no result columns")
+	errMultiStatement    = errors.New("query must be a single statement")
+)
+
+func snakeCaseToPascalCase(s string) string {
+	if len(s) < 1 {
+		return ""
+	}
+	var buf strings.Builder
+	c1 := s[0]
+	if c1 >= 'a' && c1 <= 'z' {
+		c1 = c1 - 'a' + 'A'
+	}
+	buf.WriteByte(c1)
+	for i := 1; i < len(s); i++ {
+		c1 = s[i]
+		if c1 == '_' {
+			i++
+			if i == len(s) {
+				break
+			}
+			c1 = s[i]
+			if c1 >= 'a' && c1 <= 'z' {
+				c1 = c1 - 'a' + 'A'
+			}
+		}
+		buf.WriteByte(c1)
+	}
+	return buf.String()
+}
+
+func pascalCaseToCamelCase(s string) string {
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func parseError(s string) error {
+	return fmt.Errorf("parse error '%s'", s)
+}
+
+// go类型对应的sql.NullX，[]byte可以是nil，不需要
+func goNullType(typ string) string {
+	switch {
+	case typ == "[]byte":
+		return typ
+	case typ == "bool":
+		return "sql.NullBool"
+	case typ == "time.Time":
+		return "sql.NullTime"
+	case strings.Contains(typ, "int"):
+		return "sql.NullInt64"
+	case strings.Contains(typ, "float"):
+		return "sql.NullFloat64"
+	default:
+		return "sql.NullString"
+	}
+}
+
+// ScanBackend.ScanFunc可以转换的类型
+var scanFuncTypes = map[string]bool{
+	"int64": true, "float64": true, "bool": true, "string": true, "[]byte": true,
+}
+
+func dbColumnToScanTPL(c *Column, nullField bool, scanFunc string) *scanTPL {
+	t := new(scanTPL)
+	t.Name = snakeCaseToPascalCase(strings.Replace(c.Name, ".", "_", -1))
+	t.Type = c.GoType
+	if scanFuncTypes[t.Type] {
+		t.Func = scanFunc
+	}
+	if nullField && c.Nullable {
+		switch t.NullType = goNullType(t.Type); t.NullType {
+		case "sql.NullInt64":
+			t.NullValue = "Int64"
+			t.NullType2 = "int64"
+		case "sql.NullFloat64":
+			t.NullValue = "Float64"
+			t.NullType2 = "float64"
+		case "sql.NullBool":
+			t.NullValue = "Bool"
+			t.NullType2 = "bool"
+		case "sql.NullTime":
+			t.NullValue = "Time"
+			t.NullType2 = "time.Time"
+		case "sql.NullString":
+			t.NullValue = "String"
+			t.NullType2 = "string"
+		default:
+			t.NullType = ""
+		}
+	}
+	return t
+}
+
+func dbColumnToField(c *Column, nullField bool) [3]string {
+	var field [3]string
+	field[0] = snakeCaseToPascalCase(c.Name)
+	field[1] = dbColumnToFieldType(c, nullField)
+	field[2] = "`" + fmt.Sprintf(`json:"%s"`, pascalCaseToCamelCase(field[0])) + "`"
+	return field
+}
+
+func dbColumnToFieldType(c *Column, nullField bool) string {
+	if nullField && c.Nullable {
+		return goNullType(c.GoType)
+	}
+	return c.GoType
+}
+
+// 给参数编号，ReuseParam时名称相同的参数使用同一个占位符，返回函数的参数
+func numberParams(b Backend, params sqlSegments) (sqlSegments, error) {
+	if !b.ReuseParam() {
+		for i, p := range params {
+			p.placeholder = b.Placeholder(i + 1)
+		}
+		return params, nil
+	}
+	var unique sqlSegments
+	index := make(map[string]*sqlSegment)
+	for _, p := range params {
+		if q, ok := index[p.string]; ok {
+			if q.value != p.value {
+				return nil, fmt.Errorf("param '%s' has different types '%s' and '%s'", p.string, q.value, p.value)
+			}
+			p.placeholder = q.placeholder
+			continue
+		}
+		unique = append(unique, p)
+		p.placeholder = b.Placeholder(len(unique))
+		index[p.string] = p
+	}
+	return unique, nil
+}
+
+// 打开dbUrl的连接池用于测试sql，使用完需要Close
+func NewCode(b Backend, pkg, driver, dbUrl string) (*Code, error) {
+	db, err := b.Open(dbUrl)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewCodeDB(b, pkg, driver, db)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	c.closeDB = true
+	return c, nil
+}
+
+// 使用外部的连接池测试sql，Close不会关闭db，driver为空使用Backend.DriverPkg()
+func NewCodeDB(b Backend, pkg, driver string, db *sql.DB) (*Code, error) {
+	// 数据库结构，用于生成CheckSchema和分析执行计划
+	schema, err := readSchema(b, db)
+	if err != nil {
+		return nil, err
+	}
+	c := newCode(b, pkg, driver, schema)
+	c.db = db
+	return c, nil
+}
+
+// 离线生成，不连接数据库，使用schema（比如db2go保存的json）解析sql的结果集，
+// 不能推断类型的表达式需要在QueryTypes的types中指定
+func NewCodeSchema(b Backend, pkg, driver string, schema *db2go.Schema) (*Code, error) {
+	if schema == nil {
+		return nil, errNilSchema
+	}
+	return newCode(b, pkg, driver, schema), nil
+}
+
+func newCode(b Backend, pkg, driver string, schema *db2go.Schema) *Code {
+	if driver == "" {
+		driver = b.DriverPkg()
+	}
+	c := new(Code)
+	c.backend = b
+	c.schema = schema
+	c.file = new(fileTPL)
+	c.file.Pkg = pkg
+	c.file.Driver = driver
+	c.file.DriverName = b.DriverName()
+	c.file.ErrorHelper = b.ErrorHelper(driver)
+	if sb, ok := b.(SchemaBackend); ok {
+		c.file.DBType = sb.DBType()
+	}
+	c.file.Fingerprint = make(db2go.Fingerprint)
+	c.file.FuncTables = make(map[string][]string)
+	return c
+}
+
+type Code struct {
+	backend     Backend
+	file        *fileTPL
+	db          *sql.DB       // 测试sql的连接池，离线生成时为nil
+	closeDB     bool          // Close时是否关闭db
+	schema      *db2go.Schema // 数据库结构，Backend不能读取时为nil
+	explainRows int64         // 表的估算行数超过这个值才警告，0不分析执行计划
+	selectOnly  bool          // Query只能是select
+	warning     []string      // 生成代码时的警告
+}
+
+// 设置后，Query会分析sql的执行计划，对行数超过minRows的表的全表扫描等问题给出警告
+func (c *Code) SetExplain(minRows int64) {
+	if minRows > 0 && c.schema == nil {
+		c.warning = append(c.warning, "explain is not supported by this database")
+		return
+	}
+	if minRows > 0 && c.db == nil {
+		c.warning = append(c.warning, "explain is not supported without database")
+		return
+	}
+	c.explainRows = minRows
+}
+
+// 设置后，Query的sql只能是select，不能修改数据，比如http生成时
+func (c *Code) SetSelectOnly(selectOnly bool) {
+	c.selectOnly = selectOnly
+}
+
+// sql.Open的驱动名称，空则使用Backend.DriverName()
+func (c *Code) SetDriverName(name string) {
+	if name != "" {
+		c.file.DriverName = name
+	}
+}
+
+// 数据库结构，Backend不能读取时为nil
+func (c *Code) Schema() *db2go.Schema {
+	return c.schema
+}
+
+// 生成代码时的警告
+func (c *Code) Warnings() []string {
+	return c.warning
+}
+
+// 测试sql的连接池，离线生成时为nil
+func (c *Code) DB() *sql.DB {
+	return c.db
+}
+
+// 关闭NewCode打开的连接池
+func (c *Code) Close() error {
+	if !c.closeDB {
+		return nil
+	}
+	return c.db.Close()
+}
+
+func (c *Code) SaveFile(file string) error {
+	// 创建目录
+	dir := filepath.Dir(file)
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	// 输出模板
+	f, err := os.OpenFile(file, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	// 关闭文件
+	defer func() { _ = f.Close() }()
+	// 输出
+	return c.file.Execute(f)
+}
+
+func (c *Code) resolver(types map[string]string) *sqlResolver {
+	return &sqlResolver{backend: c.backend, schema: c.schema, types: types}
+}
+
+// 在事务中测试sql，返回结果集的字段信息，事务总是回滚，sql不会修改数据
+func (c *Code) testQuery(_sql string, args []interface{}) ([]*Column, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	// 预处理只能有一条语句，不会执行后面的commit等
+	stmt, err := tx.Prepare(_sql)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	return c.backend.Columns(rows)
+}
+
+// 输出代码
+func (c *Code) Write(w io.Writer) error {
+	return c.file.Execute(w)
+}
+
+func (c *Code) Exec(originalSql, function, tx string) (TPL, error) {
+	if originalSql == "" {
+		return nil, errEmptySQL
+	}
+	if function == "" {
+		return nil, errEmptyFunctionName
+	}
+	// 解析sql
+	segments, paramSegments, _, err := parseSegments(originalSql)
+	if err != nil {
+		return nil, err
+	}
+	paramSegments, err = numberParams(c.backend, paramSegments)
+	if err != nil {
+		return nil, err
+	}
+	// 重新写sql
+	var _sql strings.Builder
+	{
+		for _, s := range segments {
+			if s.param {
+				_sql.WriteString(s.placeholder)
+			} else {
+				_sql.WriteString(s.string)
+			}
+		}
+	}
+	// 测试sql，离线时检查表和列
+	if c.db == nil {
+		err = c.resolver(nil).Exec(_sql.String())
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		stmt, err := c.db.Prepare(_sql.String())
+		if err != nil {
+			return nil, err
+		}
+		_ = stmt.Close()
+	}
+	c.addFingerprint(function, _sql.String())
+	// 公共模板
+	var tp tpl
+	tp.Func = function
+	tp.Tx = tx
+	tp.Sql = _sql.String()
+	tp.Stmt = c.file.StmtName(tp.Sql)
+	// 只有一个入参，不生成结构体
+	if paramSegments != nil && len(paramSegments) < 2 {
+		t := new(execTPL)
+		t.tpl = tp
+		t.Param = append(t.Param, pascalCaseToCamelCase(snakeCaseToPascalCase(paramSegments[0].string)))
+		c.file.Func = append(c.file.Func, t)
+		return t, nil
+	}
+	// 有多个入参，生成结构体
+	t := new(execStructTPL)
+	t.tpl = tp
+	t.Struct = function + "Model"
+	for _, s := range paramSegments {
+		var field [3]string
+		field[0] = snakeCaseToPascalCase(s.string)
+		switch s.value {
+		case "nstring":
+			field[1] = "sql.NullString"
+		case "nint":
+			field[1] = "sql.NullInt64"
+		case "nfloat":
+			field[1] = "sql.NullFloat64"
+		default:
+			field[1] = s.value
+		}
+		field[2] = fmt.Sprintf("`json:\"%s\"`", pascalCaseToCamelCase(field[0]))
+		t.Field = append(t.Field, field)
+	}
+	c.file.Func = append(c.file.Func, t)
+	return t, nil
+}
+
+func (c *Code) Query(originalSql, function, tx string, isRow, nullField bool) (TPL, error) {
+	return c.QueryTypes(originalSql, function, tx, isRow, nullField, nil)
+}
+
+// types指定结果集的列（或别名）的go类型，离线生成时不能推断类型的表达式必须指定
+func (c *Code) QueryTypes(originalSql, function, tx string, isRow, nullField bool, types map[string]string) (TPL, error) {
+	if originalSql == "" {
+		return nil, errEmptySQL
+	}
+	if function == "" {
+		return nil, errEmptyFunctionName
+	}
+	// 解析sql
+	segments, paramSegments, columnSegments, err := parseSegments(originalSql)
+	if err != nil {
+		return nil, err
+	}
+	paramSegments, err = numberParams(c.backend, paramSegments)
+	if err != nil {
+		return nil, err
+	}
+	// 重新写sql
+	var _sql strings.Builder
+	var testArgs []interface{}
+	{
+		for _, seg := range segments {
+			if seg.param {
+				if seg.column {
+					_sql.WriteString(seg.value)
+				} else {
+					_sql.WriteString(seg.placeholder)
+				}
+			} else {
+				_sql.WriteString(seg.string)
+			}
+		}
+		for _, seg := range paramSegments {
+			testArgs = append(testArgs, c.backend.TestValue(seg.value))
+		}
+	}
+	if c.selectOnly && !isSelectSQL(_sql.String(), c.backend.Syntax()) {
+		return nil, errNotSelect
+	}
+	if !isSingleSQL(_sql.String(), c.backend.Syntax()) {
+		return nil, errMultiStatement
+	}
+	// 测试sql，获取结果集的字段信息
+	var results []*Column
+	if c.db == nil {
+		// 离线解析
+		results, err = c.resolver(types).Query(_sql.String())
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		results, err = c.testQuery(_sql.String(), testArgs)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range results {
+			if typ, ok := types[r.Name]; ok {
+				r.GoType = typ
+			} else {
+				r.GoType = c.backend.GoType(r.DatabaseType)
+				if r.GoType == "" {
+					return nil, fmt.Errorf("can not infer the type of '%s', set it in types", r.Name)
+				}
+			}
+		}
+	}
+	if len(results) < 1 {
+		return nil, errNoResultColumn
+	}
+	c.addFingerprint(function, _sql.String())
+	// 分析执行计划
+	if c.explainRows > 0 {
+		e, err := c.schema.Explain(_sql.String(), testArgs...)
+		if err != nil {
+			return nil, err
+		}
+		for _, w := range e.Warnings(c.explainRows) {
+			c.warning = append(c.warning, fmt.Sprintf("%s: %s", function, w.String()))
+		}
+	}
+	// 公共模板
+	var qt queryTPL
+	qt.Sql = _sql.String()
+	qt.Func = function
+	qt.Tx = tx
+	qt.NullField = nullField
+	qt.Param = paramSegments.ToParam()
+	if sb, ok := c.backend.(ScanBackend); ok {
+		qt.ScanFunc = sb.ScanFunc()
+		for _, r := range results {
+			if scanFuncTypes[r.GoType] {
+				c.file.DB2Go = true
+			}
+		}
+	}
+	// 无法预编译的sql
+	if len(columnSegments) > 0 {
+		var qst querySqlTPL
+		qst.queryTPL = qt
+		qst.Column = columnSegments.ToParam()
+		qst.Segment = segments.ToTPL()
+		c.file.Strings = true
+		if isRow {
+			// 查询一行
+			if len(results) > 1 {
+				// 查询一行结构
+				t := new(querySqlStructRowTPL)
+				t.querySqlTPL = qst
+				t.InitFieldAndScan(results)
+				c.file.Func = append(c.file.Func, t)
+				return t, nil
+			}
+			// 查询一行单值
+			t := new(querySqlRowTPL)
+			t.querySqlTPL = qst
+			t.InitScan(results[0])
+			c.file.Func = append(c.file.Func, t)
+			return t, nil
+		}
+		// 查询多行
+		if len(results) > 1 {
+			// 查询多行结构
+			t := new(querySqlStructRowsTPL)
+			t.querySqlTPL = qst
+			t.InitFieldAndScan(results)
+			c.file.Func = append(c.file.Func, t)
+			return t, nil
+		}
+		// 查询多行单值
+		t := new(querySqlRowsTPL)
+		t.querySqlTPL = qst
+		t.InitScan(results[0])
+		c.file.Func = append(c.file.Func, t)
+		return t, nil
+	}
+	// 可以预编译的sql
+	qt.Stmt = c.file.StmtName(qt.Sql)
+	// 只有一个结果，不生成结构体
+	if len(results) < 2 {
+		// 单行
+		if isRow {
+			t := new(queryRowTPL)
+			t.queryTPL = qt
+			t.InitScan(results[0])
+			c.file.Func = append(c.file.Func, t)
+			return t, nil
+		}
+		// 多行
+		t := new(queryRowsTPL)
+		t.queryTPL = qt
+		t.InitScan(results[0])
+		c.file.Func = append(c.file.Func, t)
+		return t, nil
+	}
+	// 有多个结果，生成结构体
+	if isRow {
+		// 查询单行
+		t := new(queryStructRowTPL)
+		t.queryTPL = qt
+		t.InitFieldAndScan(results)
+		c.file.Func = append(c.file.Func, t)
+		return t, nil
+	}
+	// 查询多行
+	t := new(queryStructRowsTPL)
+	t.queryTPL = qt
+	t.InitFieldAndScan(results)
+	c.file.Func = append(c.file.Func, t)
+	return t, nil
+}
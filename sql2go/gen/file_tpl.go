@@ -0,0 +1,157 @@
+package gen
+
+import (
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+var _fileTPL = template.Must(template.New("fileTPL").Parse(`package {{.Pkg}}
+
+import (
+	"database/sql"
+	{{if .ErrorHelper -}}
+	"{{.Driver}}"
+	{{else -}}
+	_ "{{.Driver}}"
+	{{end -}}
+	{{if or .DBType .DB2Go -}}
+	"github.com/qq51529210/db/db2go"
+	{{end -}}
+	{{if .Strings -}}
+	"strings"
+	{{end -}}
+	"time"
+)
+
+var (
+	DB *sql.DB
+	{{- range $i,$s := .Sql}}
+	stmt{{$i}} *sql.Stmt // {{$s}}
+	{{- end}}
+)
+
+{{.ErrorHelper}}
+{{- if .DBType}}
+
+// 生成代码时，函数依赖的表和列
+var schemaFingerprint = db2go.Fingerprint{
+	{{- range $t,$c := .Fingerprint}}
+	{{- if $c}}
+	{{printf "%q" $t}}: {
+		{{- range $n,$typ := $c}}
+		{{printf "%q" $n}}: {{printf "%q" $typ}},
+		{{- end}}
+	},
+	{{- else}}
+	{{printf "%q" $t}}: {},
+	{{- end}}
+	{{- end}}
+}
+
+// 每个函数依赖的表
+var schemaFuncTables = map[string][]string{
+	{{- range $f,$t := .FuncTables}}
+	{{printf "%q" $f}}: { {{- range $i,$n := $t}}{{if $i}}, {{end}}{{printf "%q" $n}}{{end -}} },
+	{{- end}}
+}
+
+// 检查数据库的表和列是否与生成代码时一致，返回所有不一致的地方，用于启动时检查
+func CheckSchema(db *sql.DB) error {
+	schema, err := db2go.ReadSchemaDB({{.DBTypeName}}, db)
+	if err != nil {
+		return err
+	}
+	return schema.CheckFingerprint(schemaFingerprint, schemaFuncTables)
+}
+{{- end}}
+
+func Init(url string, maxOpen, maxIdle int, maxLifeTime, maxIdleTime time.Duration) (err error){
+	DB, err = sql.Open({{printf "%q" .DriverName}}, url)
+	if err != nil {
+		return err
+	}
+	DB.SetMaxOpenConns(maxOpen)
+	DB.SetMaxIdleConns(maxIdle)
+	DB.SetConnMaxLifetime(maxLifeTime)
+	DB.SetConnMaxIdleTime(maxIdleTime)
+	return PrepareStmt(DB)
+}
+
+func UnInit() {
+	if DB == nil {
+		return
+	}
+	_ = DB.Close()
+	CloseStmt()
+}
+
+func PrepareStmt(db *sql.DB) (err error) {
+	{{- range $i,$s:= .Sql}}
+	stmt{{$i}}, err = db.Prepare({{printf "%q" $s}})
+	if err != nil {
+		return
+	}
+	{{- end}}
+	return
+}
+
+func CloseStmt() {
+	{{- range $i,$s:= .Sql}}
+	if stmt{{$i}} != nil {
+		_ = stmt{{$i}}.Close()
+	}
+	{{- end}}
+}
+
+{{- range .Func}}
+{{$.FuncString .}}
+{{- end}}
+`))
+
+type fileTPL struct {
+	Pkg         string
+	Driver      string // 驱动包
+	DriverName  string // sql.Open的驱动名称
+	ErrorHelper string // IsUniqueKeyError等函数
+	DBType      string // 不为空时生成CheckSchema
+	DB2Go       bool   // 使用了db2go的函数，比如ScanBackend.ScanFunc
+	Strings     bool   // import
+	Sql         []string
+	Func        []TPL
+	Fingerprint db2go.Fingerprint   // 函数依赖的表和列
+	FuncTables  map[string][]string // 函数依赖的表
+}
+
+func (t *fileTPL) Execute(w io.Writer) error {
+	return _fileTPL.Execute(w, t)
+}
+
+// 生成的代码中的数据库类型，使用db2go的常量
+func (t *fileTPL) DBTypeName() string {
+	switch t.DBType {
+	case db2go.MYSQL:
+		return "db2go.MYSQL"
+	case db2go.POSTGRES:
+		return "db2go.POSTGRES"
+	case db2go.SQLITE:
+		return "db2go.SQLITE"
+	default:
+		return strconv.Quote(t.DBType)
+	}
+}
+
+func (t *fileTPL) FuncString(tpl TPL) string {
+	var str strings.Builder
+	_ = tpl.Execute(&str)
+	return str.String()
+}
+
+func (t *fileTPL) StmtName(s string) string {
+	stmt := fmt.Sprintf("stmt%d", len(t.Sql))
+	t.Sql = append(t.Sql, s)
+	return stmt
+}
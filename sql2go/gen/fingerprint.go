@@ -0,0 +1,86 @@
+package gen
+
+import (
+	"sort"
+	"strings"
+)
+
+// sql中出现的标识符（小写），star表示有select *或者t.*
+func sqlIdentifiers(s string) (names map[string]bool, star bool) {
+	names = make(map[string]bool)
+	prev := ""
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\'' || c == '"':
+			// 字符串
+			j := i + 1
+			for j < len(s) && s[j] != c {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			i = j + 1
+			prev = "'"
+		case c == '`':
+			j := strings.IndexByte(s[i+1:], '`')
+			if j < 0 {
+				return
+			}
+			prev = strings.ToLower(s[i+1 : i+1+j])
+			names[prev] = true
+			i += j + 2
+		case c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || s[j] == '$' || (s[j] >= 'a' && s[j] <= 'z') || (s[j] >= 'A' && s[j] <= 'Z') || (s[j] >= '0' && s[j] <= '9')) {
+				j++
+			}
+			prev = strings.ToLower(s[i:j])
+			names[prev] = true
+			i = j
+		case c == '*':
+			// count(*)和乘法不算
+			if prev == "select" || prev == "," || prev == "." || prev == "distinct" {
+				star = true
+			}
+			prev = "*"
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		default:
+			prev = string(c)
+			i++
+		}
+	}
+	return
+}
+
+// 记录函数依赖的表和列，用于生成CheckSchema
+func (c *Code) addFingerprint(function, _sql string) {
+	if c.schema == nil {
+		return
+	}
+	names, star := sqlIdentifiers(_sql)
+	var tables []string
+	for _, t := range c.schema.Tables() {
+		if !names[strings.ToLower(t.Name())] {
+			continue
+		}
+		tables = append(tables, t.Name())
+		var columns []string
+		for _, col := range t.Columns() {
+			if star || names[strings.ToLower(col.Name())] {
+				columns = append(columns, col.Name())
+			}
+		}
+		// 只用到表名，比如count(*)，只检查表是否存在
+		if len(columns) < 1 {
+			c.file.Fingerprint.AddTable(t.Name())
+			continue
+		}
+		c.file.Fingerprint.Merge(c.schema.Fingerprint(t.Name(), columns...))
+	}
+	sort.Strings(tables)
+	c.file.FuncTables[function] = tables
+}
@@ -1,7 +1,6 @@
-package mysql
+package gen
 
 import (
-	"database/sql"
 	"io"
 	"text/template"
 )
@@ -14,7 +13,7 @@ func {{.Func}}({{.ParamTPL}}) ({{.Scan.NullType}}, error) {
 		{{- range .Param}}
 		{{.}},
 		{{- end}}
-	).Scan(&model)
+	).Scan({{.Scan.Dest "model"}})
 }`))
 
 var _queryRowTPL = template.Must(template.New("queryRowTPL").Parse(`
@@ -26,7 +25,7 @@ func {{.Func}}({{.ParamTPL}}) ({{.Scan.Type}}, error) {
 		{{- range .Param}}
 		{{.}},
 		{{- end}}
-	).Scan(&model)
+	).Scan({{.Scan.Dest "model"}})
 	if err != nil {
 		return model.{{.Scan.NullValue}}, err
 	}
@@ -41,7 +40,7 @@ func {{.Func}}({{.ParamTPL}}) ({{.Scan.Type}}, error) {
 		{{- range .Param}}
 		{{.}},
 		{{- end}}
-	).Scan(&model)
+	).Scan({{.Scan.Dest "model"}})
 	{{- end}}
 }`))
 
@@ -57,8 +56,8 @@ func (t *queryRowTPL) Execute(w io.Writer) error {
 	return _queryRowTPL.Execute(w, t)
 }
 
-func (t *queryRowTPL) InitScan(column *sql.ColumnType) {
-	t.Scan = dbColumnToScanTPL(column, t.NullField)
+func (t *queryRowTPL) InitScan(column *Column) {
+	t.Scan = dbColumnToScanTPL(column, t.NullField, t.ScanFunc)
 	// 如果数据库字段不为null，那么NullField无效
 	if t.NullField {
 		t.NullField = t.Scan.NullType != ""
@@ -1,7 +1,6 @@
-package mysql
+package gen
 
 import (
-	"database/sql"
 	"io"
 	"text/template"
 )
@@ -18,7 +17,7 @@ func {{.Func}}({{.ParamTPL}}) ({{.Scan.NullType}}, error) {
 		{{- range .Param}}
 		{{.}},
 		{{- end}}
-	).Scan(&model)
+	).Scan({{.Scan.Dest "model"}})
 }`))
 
 var _querySqlRowTPL = template.Must(template.New("querySqlRowTPL").Parse(`
@@ -35,7 +34,7 @@ func {{.Func}}({{.ParamTPL}}) ({{.Scan.Type}}, error) {
 		{{- range .Param}}
 		{{.}},
 		{{- end}}
-	).Scan(&model)
+	).Scan({{.Scan.Dest "model"}})
 	if err != nil {
 		return model.{{.Scan.NullValue}}, err
 	}
@@ -50,7 +49,7 @@ func {{.Func}}({{.ParamTPL}}) ({{.Scan.Type}}, error) {
 		{{- range .Param}}
 		{{.}},
 		{{- end}}
-	).Scan(&model)
+	).Scan({{.Scan.Dest "model"}})
 	{{- end}}
 }`))
 
@@ -66,8 +65,8 @@ func (t *querySqlRowTPL) Execute(w io.Writer) error {
 	return _querySqlRowTPL.Execute(w, t)
 }
 
-func (t *querySqlRowTPL) InitScan(column *sql.ColumnType) {
-	t.Scan = dbColumnToScanTPL(column, t.NullField)
+func (t *querySqlRowTPL) InitScan(column *Column) {
+	t.Scan = dbColumnToScanTPL(column, t.NullField, t.ScanFunc)
 	// 如果数据库字段不为null，那么NullField无效
 	if t.NullField {
 		t.NullField = t.Scan.NullType != ""
@@ -1,4 +1,4 @@
-package mysql
+package gen
 
 import (
 	"io"
@@ -24,7 +24,7 @@ func {{.Func}}({{.ParamTPL}}) ([]{{.Scan.NullType}}, error) {
 	var models []{{.Scan.NullType}}
 	var model {{.Scan.NullType}}
 	for rows.Next() {
-		err = rows.Scan(&model)
+		err = rows.Scan({{.Scan.Dest "model"}})
 		if nil != err {
 			return nil, err
 		}
@@ -56,7 +56,7 @@ func {{.Func}}({{.ParamTPL}}) ([]{{.Scan.Type}}, error) {
 	var model {{.Scan.Type}}
 	{{- end}}
 	for rows.Next() {
-		err = rows.Scan(&model)
+		err = rows.Scan({{.Scan.Dest "model"}})
 		if nil != err {
 			return nil, err
 		}
@@ -1,4 +1,4 @@
-package mysql
+package gen
 
 import (
 	"io"
@@ -26,7 +26,7 @@ func {{.Func}}({{.ParamTPL}}) (*{{.Func}}Model, error) {
 		{{- end}}
 	).Scan(
 		{{- range .Scan}}
-		&model.{{.Name}},
+		{{.Dest (print "model." .Name)}},
 		{{- end}}
 	)
 }`))
@@ -58,9 +58,9 @@ func {{.Func}}({{.ParamTPL}}) (*{{.Func}}Model, error) {
 	).Scan(
 		{{- range .Scan}}
 		{{- if .NullType}}
-		&{{.Name}},
+		{{.Dest .Name}},
 		{{- else}}
-		&model.{{.Name}},
+		{{.Dest (print "model." .Name)}},
 		{{- end}}
 		{{- end}}
 	)
@@ -1,4 +1,4 @@
-package mysql
+package gen
 
 import (
 	"io"
@@ -32,7 +32,7 @@ func {{.Func}}({{.ParamTPL}}) ([]*{{.Func}}Model, error) {
 		model := new({{.Func}}Model)
 		err = rows.Scan(
 			{{- range .Scan}}
-			&model.{{.Name}},
+			{{.Dest (print "model." .Name)}},
 			{{- end}}
 		)
 		if nil != err {
@@ -76,9 +76,9 @@ func {{.Func}}({{.ParamTPL}}) ([]*{{.Func}}Model, error) {
 		err = rows.Scan(
 			{{- range .Scan}}
 			{{- if .NullType}}
-			&{{.Name}},
+			{{.Dest .Name}},
 			{{- else}}
-			&model.{{.Name}},
+			{{.Dest (print "model." .Name)}},
 			{{- end}}
 			{{- end}}
 		)
@@ -1,4 +1,4 @@
-package mysql
+package gen
 
 import (
 	"io"
@@ -21,7 +21,7 @@ func {{.Func}}({{.ParamTPL}}) (*{{.Func}}Model, error) {
 		{{- end}}
 	).Scan(
 		{{- range .Scan}}
-		&model.{{.Name}},
+		{{.Dest (print "model." .Name)}},
 		{{- end}}
 	)
 }`))
@@ -48,9 +48,9 @@ func {{.Func}}({{.ParamTPL}}) (*{{.Func}}Model, error) {
 	).Scan(
 		{{- range .Scan}}
 		{{- if .NullType}}
-		&{{.Name}},
+		{{.Dest .Name}},
 		{{- else}}
-		&model.{{.Name}},
+		{{.Dest (print "model." .Name)}},
 		{{- end}}
 		{{- end}}
 	)
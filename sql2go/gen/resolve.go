@@ -0,0 +1,612 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"github.com/qq51529210/db/db2go"
+	"strings"
+)
+
+var (
+	errNilSchema     = errors.New("nil schema")
+	errOfflineSelect = errors.New("only select can be resolved without database")
+)
+
+// from子句后面的关键字
+var sqlFromEnd = map[string]bool{
+	"where": true, "group": true, "order": true, "having": true, "limit": true, "offset": true, "union": true,
+	"window": true, "for": true, "lock": true, "into": true, "fetch": true, "returning": true,
+}
+
+// 拼接成sql，用于表达式的名称和错误信息
+func sqlTokensText(tokens []*sqlToken) string {
+	var str strings.Builder
+	for i, t := range tokens {
+		if i > 0 && t.kind != sqlTokenSymbol && tokens[i-1].kind != sqlTokenSymbol {
+			str.WriteByte(' ')
+		}
+		switch t.kind {
+		case sqlTokenString:
+			str.WriteString("'" + t.text + "'")
+		case sqlTokenQuoted:
+			str.WriteString("`" + t.text + "`")
+		default:
+			str.WriteString(t.text)
+		}
+	}
+	return str.String()
+}
+
+// tokens[i]是"("，返回对应的")"，没有返回-1
+func closeParen(tokens []*sqlToken, i int) int {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		switch {
+		case tokens[i].isSymbol("("):
+			depth++
+		case tokens[i].isSymbol(")"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// 不在括号中的第一个满足match的词，没有返回-1
+func indexTopToken(tokens []*sqlToken, match func(*sqlToken) bool) int {
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.isSymbol("("):
+			depth++
+		case t.isSymbol(")"):
+			depth--
+		case depth == 0 && match(t):
+			return i
+		}
+	}
+	return -1
+}
+
+// 用不在括号中的逗号拆分
+func splitTopComma(tokens []*sqlToken) [][]*sqlToken {
+	var items [][]*sqlToken
+	for {
+		i := indexTopToken(tokens, func(t *sqlToken) bool { return t.isSymbol(",") })
+		if i < 0 {
+			return append(items, tokens)
+		}
+		items = append(items, tokens[:i])
+		tokens = tokens[i+1:]
+	}
+}
+
+// 去掉外层的括号
+func trimParen(tokens []*sqlToken) []*sqlToken {
+	for len(tokens) > 1 && tokens[0].isSymbol("(") && closeParen(tokens, 0) == len(tokens)-1 {
+		tokens = tokens[1 : len(tokens)-1]
+	}
+	return tokens
+}
+
+// 解析join关键字，返回join的类型（inner，left，right，full）和关键字的个数，不是join返回0
+func parseJoin(tokens []*sqlToken) (string, int) {
+	typ := "inner"
+	for i, t := range tokens {
+		switch {
+		case t.is("join") || t.is("straight_join"):
+			return typ, i + 1
+		case t.is("left") || t.is("right") || t.is("full"):
+			// left()等函数
+			if i+1 < len(tokens) && tokens[i+1].isSymbol("(") {
+				return "", 0
+			}
+			typ = strings.ToLower(t.text)
+		case t.is("inner") || t.is("outer") || t.is("cross") || t.is("natural"):
+		default:
+			return "", 0
+		}
+	}
+	return "", 0
+}
+
+// 结果集的来源，表或者子查询
+type sqlSource struct {
+	name     string    // 别名或者表名
+	columns  []*Column // 所有的列
+	nullable bool      // outer join的另一边，所有的列都可能是NULL
+}
+
+// 查找列，不区分大小写，返回的是复制
+func (s *sqlSource) column(name string) *Column {
+	for _, c := range s.columns {
+		if strings.EqualFold(c.Name, name) {
+			return s.copyColumn(c)
+		}
+	}
+	return nil
+}
+
+func (s *sqlSource) copyColumn(c *Column) *Column {
+	cc := *c
+	cc.Nullable = cc.Nullable || s.nullable
+	return &cc
+}
+
+func (s *sqlSource) allColumns() []*Column {
+	var columns []*Column
+	for _, c := range s.columns {
+		columns = append(columns, s.copyColumn(c))
+	}
+	return columns
+}
+
+// 使用数据库结构解析sql，离线生成时代替测试sql
+type sqlResolver struct {
+	backend Backend
+	schema  *db2go.Schema
+	types   map[string]string // 结果集的列名（或别名）对应的go类型
+}
+
+// 查找表，先区分大小写
+func (r *sqlResolver) table(name string) *db2go.Table {
+	if t := r.schema.GetTable(name); t != nil {
+		return t
+	}
+	for _, t := range r.schema.Tables() {
+		if strings.EqualFold(t.Name(), name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// 表的列，"int(11) unsigned"这样的类型去掉参数，再由Backend转换成go类型
+func (r *sqlResolver) tableColumns(t *db2go.Table) []*Column {
+	var columns []*Column
+	for _, c := range t.Columns() {
+		columns = append(columns, &Column{
+			Name:         c.Name(),
+			DatabaseType: c.Type(),
+			Nullable:     c.IsNullable(),
+			GoType:       r.backend.DeclaredGoType(c.Type()),
+		})
+	}
+	return columns
+}
+
+// 解析query的结果集
+func (r *sqlResolver) Query(_sql string) ([]*Column, error) {
+	tokens, err := tokenizeSQL(_sql, r.backend.Syntax())
+	if err != nil {
+		return nil, err
+	}
+	return r.resolveSelect(tokens)
+}
+
+func (r *sqlResolver) resolveSelect(tokens []*sqlToken) ([]*Column, error) {
+	// (select ...) union ...，使用第一个select
+	if len(tokens) > 0 && tokens[0].isSymbol("(") {
+		i := closeParen(tokens, 0)
+		if i < 0 {
+			return nil, parseError(sqlTokensText(tokens))
+		}
+		return r.resolveSelect(tokens[1:i])
+	}
+	if len(tokens) < 1 || !tokens[0].is("select") {
+		return nil, errOfflineSelect
+	}
+	tokens = tokens[1:]
+	for len(tokens) > 0 && (tokens[0].is("distinct") || tokens[0].is("all") || tokens[0].is("distinctrow")) {
+		tokens = tokens[1:]
+	}
+	// 结果集和from子句
+	var sources []*sqlSource
+	list := tokens
+	i := indexTopToken(tokens, func(t *sqlToken) bool {
+		return t.is("from") || sqlFromEnd[strings.ToLower(t.text)] && t.kind == sqlTokenIdent
+	})
+	if i >= 0 {
+		list = tokens[:i]
+		if tokens[i].is("from") {
+			var err error
+			sources, err = r.resolveFrom(tokens[i+1:])
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	var columns []*Column
+	for _, item := range splitTopComma(list) {
+		cs, err := r.resolveItem(item, sources)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, cs...)
+	}
+	return columns, nil
+}
+
+// 解析from子句中的表，子查询和join
+func (r *sqlResolver) resolveFrom(tokens []*sqlToken) ([]*sqlSource, error) {
+	if i := indexTopToken(tokens, func(t *sqlToken) bool {
+		return t.isSymbol(";") || (t.kind == sqlTokenIdent && sqlFromEnd[strings.ToLower(t.text)])
+	}); i >= 0 {
+		tokens = tokens[:i]
+	}
+	var sources []*sqlSource
+	join := ""
+	i := 0
+	for i < len(tokens) {
+		// 表或者子查询
+		s := new(sqlSource)
+		switch t := tokens[i]; {
+		case t.isSymbol("("):
+			j := closeParen(tokens, i)
+			if j < 0 {
+				return nil, parseError(sqlTokensText(tokens[i:]))
+			}
+			columns, err := r.resolveSelect(tokens[i+1 : j])
+			if err != nil {
+				return nil, err
+			}
+			s.columns = columns
+			i = j + 1
+		case t.isName():
+			name := t.text
+			i++
+			// db.table
+			for i+1 < len(tokens) && tokens[i].isSymbol(".") && tokens[i+1].isName() {
+				name = tokens[i+1].text
+				i += 2
+			}
+			table := r.table(name)
+			if table == nil {
+				return nil, fmt.Errorf("unknown table '%s'", name)
+			}
+			s.name = table.Name()
+			s.columns = r.tableColumns(table)
+		default:
+			return nil, parseError(sqlTokensText(tokens[i:]))
+		}
+		// 别名
+		if i < len(tokens) && tokens[i].is("as") {
+			i++
+		}
+		if i < len(tokens) && tokens[i].isName() {
+			s.name = tokens[i].text
+			i++
+		}
+		// outer join
+		switch join {
+		case "left":
+			s.nullable = true
+		case "right":
+			for _, p := range sources {
+				p.nullable = true
+			}
+		case "full":
+			s.nullable = true
+			for _, p := range sources {
+				p.nullable = true
+			}
+		}
+		sources = append(sources, s)
+		// 跳过on和using，到下一个join或者逗号
+		join = ""
+		for i < len(tokens) && join == "" {
+			if tokens[i].isSymbol("(") {
+				j := closeParen(tokens, i)
+				if j < 0 {
+					return nil, parseError(sqlTokensText(tokens[i:]))
+				}
+				i = j + 1
+				continue
+			}
+			if tokens[i].isSymbol(",") {
+				join = "inner"
+				i++
+				continue
+			}
+			if typ, n := parseJoin(tokens[i:]); n > 0 {
+				join = typ
+				i += n
+				continue
+			}
+			i++
+		}
+	}
+	if join != "" {
+		return nil, parseError(sqlTokensText(tokens))
+	}
+	return sources, nil
+}
+
+// 拆分"expr [as] alias"，没有别名返回空
+func splitAlias(item []*sqlToken) ([]*sqlToken, string) {
+	n := len(item)
+	if n > 2 && item[n-2].is("as") && item[n-1].isName() {
+		return item[:n-2], item[n-1].text
+	}
+	// 没有as的别名，前面不能是运算符
+	if n > 1 && item[n-1].isName() {
+		prev := item[n-2]
+		if prev.isSymbol(")") || prev.kind == sqlTokenString || prev.kind == sqlTokenNumber ||
+			prev.kind == sqlTokenQuoted || prev.kind == sqlTokenParam || (prev.kind == sqlTokenIdent && prev.isName()) ||
+			prev.is("end") || prev.is("null") || prev.is("true") || prev.is("false") {
+			return item[:n-1], item[n-1].text
+		}
+	}
+	return item, ""
+}
+
+// 解析结果集的一项
+func (r *sqlResolver) resolveItem(item []*sqlToken, sources []*sqlSource) ([]*Column, error) {
+	if len(item) < 1 {
+		return nil, errors.New("empty select item")
+	}
+	// *
+	if len(item) == 1 && item[0].isSymbol("*") {
+		if len(sources) < 1 {
+			return nil, errors.New("select * without table")
+		}
+		var columns []*Column
+		for _, s := range sources {
+			columns = append(columns, s.allColumns()...)
+		}
+		return columns, nil
+	}
+	// t.*
+	if len(item) == 3 && item[0].isName() && item[1].isSymbol(".") && item[2].isSymbol("*") {
+		for _, s := range sources {
+			if strings.EqualFold(s.name, item[0].text) {
+				return s.allColumns(), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown table '%s'", item[0].text)
+	}
+	expr, alias := splitAlias(item)
+	expr = trimParen(expr)
+	name := alias
+	if name == "" {
+		if ref := columnRef(expr); ref != nil {
+			name = ref[len(ref)-1]
+		} else {
+			name = sqlTokensText(expr)
+		}
+	}
+	// 指定的类型优先
+	if typ, ok := r.types[name]; ok {
+		return []*Column{{Name: name, GoType: typ}}, nil
+	}
+	c, err := r.inferExpr(expr, sources)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, fmt.Errorf("can not infer the type of '%s', set it in types", name)
+	}
+	c.Name = name
+	return []*Column{c}, nil
+}
+
+// 列的引用，col，t.col或者db.t.col，返回名称，不是返回nil
+func columnRef(expr []*sqlToken) []string {
+	if len(expr) < 1 || len(expr)%2 != 1 || len(expr) > 5 {
+		return nil
+	}
+	var names []string
+	for i, t := range expr {
+		if i%2 == 1 {
+			if !t.isSymbol(".") {
+				return nil
+			}
+			continue
+		}
+		if !t.isName() {
+			return nil
+		}
+		names = append(names, t.text)
+	}
+	return names
+}
+
+// 在sources中查找列，ref是columnRef的返回
+func findColumn(ref []string, sources []*sqlSource) (*Column, error) {
+	name := ref[len(ref)-1]
+	// t.col
+	if len(ref) > 1 {
+		table := ref[len(ref)-2]
+		for _, s := range sources {
+			if !strings.EqualFold(s.name, table) {
+				continue
+			}
+			if c := s.column(name); c != nil {
+				return c, nil
+			}
+			return nil, fmt.Errorf("unknown column '%s.%s'", table, name)
+		}
+		return nil, fmt.Errorf("unknown table '%s'", table)
+	}
+	// col
+	var column *Column
+	for _, s := range sources {
+		if c := s.column(name); c != nil {
+			if column != nil {
+				return nil, fmt.Errorf("ambiguous column '%s'", name)
+			}
+			column = c
+		}
+	}
+	if column == nil {
+		return nil, fmt.Errorf("unknown column '%s'", name)
+	}
+	return column, nil
+}
+
+// 推断表达式的类型，列，字面量，count，min和max，不能推断返回nil
+func (r *sqlResolver) inferExpr(expr []*sqlToken, sources []*sqlSource) (*Column, error) {
+	if ref := columnRef(expr); ref != nil {
+		return findColumn(ref, sources)
+	}
+	if len(expr) == 1 {
+		switch t := expr[0]; t.kind {
+		case sqlTokenString:
+			return &Column{GoType: "string"}, nil
+		case sqlTokenNumber:
+			if strings.ContainsAny(t.text, ".eE") {
+				return &Column{GoType: "float64"}, nil
+			}
+			return &Column{GoType: "int64"}, nil
+		case sqlTokenIdent:
+			if t.is("true") || t.is("false") {
+				return &Column{GoType: "bool"}, nil
+			}
+		}
+		return nil, nil
+	}
+	// 函数
+	if len(expr) < 3 || expr[0].kind != sqlTokenIdent || !expr[1].isSymbol("(") || closeParen(expr, 1) != len(expr)-1 {
+		return nil, nil
+	}
+	args := expr[2 : len(expr)-1]
+	switch strings.ToLower(expr[0].text) {
+	case "count":
+		return &Column{GoType: "int64"}, nil
+	case "min", "max":
+		if len(args) > 0 && args[0].is("distinct") {
+			args = args[1:]
+		}
+		ref := columnRef(trimParen(args))
+		if ref == nil {
+			return nil, nil
+		}
+		c, err := findColumn(ref, sources)
+		if err != nil {
+			return nil, err
+		}
+		// 没有行时是NULL
+		c.Nullable = true
+		return c, nil
+	}
+	return nil, nil
+}
+
+// 检查exec的表和列是否存在，只检查insert，replace，update和delete
+func (r *sqlResolver) Exec(_sql string) error {
+	tokens, err := tokenizeSQL(_sql, r.backend.Syntax())
+	if err != nil {
+		return err
+	}
+	if len(tokens) < 1 {
+		return errEmptySQL
+	}
+	first := tokens[0]
+	tokens = tokens[1:]
+	// 修饰词
+	for len(tokens) > 0 && (tokens[0].is("low_priority") || tokens[0].is("delayed") ||
+		tokens[0].is("high_priority") || tokens[0].is("ignore") || tokens[0].is("quick")) {
+		tokens = tokens[1:]
+	}
+	switch {
+	case first.is("insert") || first.is("replace"):
+		if len(tokens) > 0 && tokens[0].is("into") {
+			tokens = tokens[1:]
+		}
+		table, tokens, err := r.execTable(tokens)
+		if err != nil {
+			return err
+		}
+		// (c1,c2)
+		if len(tokens) < 1 || !tokens[0].isSymbol("(") {
+			return nil
+		}
+		i := closeParen(tokens, 0)
+		if i < 0 {
+			return parseError(sqlTokensText(tokens))
+		}
+		if len(tokens) > 1 && tokens[1].is("select") {
+			return nil
+		}
+		for _, item := range splitTopComma(tokens[1:i]) {
+			err = checkColumn(table, item)
+			if err != nil {
+				return err
+			}
+		}
+	case first.is("update"):
+		table, tokens, err := r.execTable(tokens)
+		if err != nil {
+			return err
+		}
+		// 别名
+		if len(tokens) > 0 && tokens[0].is("as") {
+			tokens = tokens[1:]
+		}
+		if len(tokens) > 0 && tokens[0].isName() {
+			tokens = tokens[1:]
+		}
+		// 多表update不检查
+		if len(tokens) < 1 || !tokens[0].is("set") {
+			return nil
+		}
+		tokens = tokens[1:]
+		if i := indexTopToken(tokens, func(t *sqlToken) bool {
+			return t.is("where") || t.is("order") || t.is("limit") || t.is("returning") || t.is("from")
+		}); i >= 0 {
+			tokens = tokens[:i]
+		}
+		for _, item := range splitTopComma(tokens) {
+			i := indexTopToken(item, func(t *sqlToken) bool { return t.isSymbol("=") })
+			if i < 0 {
+				return parseError(sqlTokensText(item))
+			}
+			err = checkColumn(table, item[:i])
+			if err != nil {
+				return err
+			}
+		}
+	case first.is("delete"):
+		if len(tokens) < 1 || !tokens[0].is("from") {
+			return nil
+		}
+		_, _, err = r.execTable(tokens[1:])
+		return err
+	}
+	return nil
+}
+
+// 解析开头的表名，返回表和剩下的词
+func (r *sqlResolver) execTable(tokens []*sqlToken) (*db2go.Table, []*sqlToken, error) {
+	if len(tokens) < 1 || !tokens[0].isName() {
+		return nil, nil, parseError(sqlTokensText(tokens))
+	}
+	name := tokens[0].text
+	tokens = tokens[1:]
+	for len(tokens) > 1 && tokens[0].isSymbol(".") && tokens[1].isName() {
+		name = tokens[1].text
+		tokens = tokens[2:]
+	}
+	table := r.table(name)
+	if table == nil {
+		return nil, nil, fmt.Errorf("unknown table '%s'", name)
+	}
+	return table, tokens, nil
+}
+
+// 检查列是否存在，ref是col或者t.col
+func checkColumn(table *db2go.Table, ref []*sqlToken) error {
+	names := columnRef(ref)
+	if names == nil {
+		return parseError(sqlTokensText(ref))
+	}
+	name := names[len(names)-1]
+	for _, c := range table.Columns() {
+		if strings.EqualFold(c.Name(), name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown column '%s.%s'", table.Name(), name)
+}
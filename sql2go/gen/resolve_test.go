@@ -0,0 +1,143 @@
+package gen
+
+import (
+	"database/sql"
+	"github.com/qq51529210/db/db2go"
+	"strings"
+	"testing"
+)
+
+// 只用于解析的Backend
+type testBackend struct{}
+
+func (testBackend) DriverPkg() string                       { return "driver" }
+func (testBackend) DriverName() string                      { return "test" }
+func (testBackend) Open(string) (*sql.DB, error)            { return nil, nil }
+func (testBackend) Placeholder(int) string                  { return "?" }
+func (testBackend) ReuseParam() bool                        { return false }
+func (testBackend) Syntax() Syntax                          { return MySQLSyntax }
+func (testBackend) TestValue(string) interface{}            { return nil }
+func (testBackend) Columns(*sql.Rows) ([]*Column, error)    { return nil, nil }
+func (testBackend) GoType(string) string                    { return "string" }
+func (testBackend) ErrorHelper(string) string               { return "" }
+func (testBackend) DeclaredGoType(columnType string) string { return testGoType(columnType) }
+
+func testGoType(columnType string) string {
+	switch {
+	case strings.HasPrefix(columnType, "int"):
+		return "int64"
+	case columnType == "double":
+		return "float64"
+	default:
+		return "string"
+	}
+}
+
+// testdata/schema.json的结构
+func testResolver(t *testing.T, types map[string]string) *sqlResolver {
+	s, err := db2go.ReadSchemaJSONFile("testdata/schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &sqlResolver{backend: testBackend{}, schema: s, types: types}
+}
+
+// 结果集的列，name:type，可以为NULL的类型前面是?
+func testColumns(columns []*Column) string {
+	var s []string
+	for _, c := range columns {
+		typ := c.GoType
+		if c.Nullable {
+			typ = "?" + typ
+		}
+		s = append(s, c.Name+":"+typ)
+	}
+	return strings.Join(s, ",")
+}
+
+func TestResolveQuery(t *testing.T) {
+	r := testResolver(t, map[string]string{"total": "sql.NullFloat64"})
+	for _, c := range []struct {
+		sql  string
+		want string
+	}{
+		// *和t.*
+		{"select * from user", "id:int64,name:string,score:?float64"},
+		{"select r.*, u.name from user u, role r", "id:int64,user_id:int64,title:string,name:string"},
+		{"select `user`.* from `user`", "id:int64,name:string,score:?float64"},
+		// 别名
+		{"select id as uid, name n, u.score s from user as u", "uid:int64,n:string,s:?float64"},
+		{"select (id) uid, 'a' str, 1 one, 1.5 half, true yes from user", "uid:int64,str:string,one:int64,half:float64,yes:bool"},
+		// join
+		{"select u.id, r.id rid from user u left join role r on u.id=r.user_id", "id:int64,rid:?int64"},
+		{"select u.id, r.id rid from user u left outer join role r using(id)", "id:int64,rid:?int64"},
+		{"select u.id, r.id rid from user u right join role r on u.id=r.user_id", "id:?int64,rid:int64"},
+		{"select u.id, r.id rid from user u full join role r on u.id=r.user_id", "id:?int64,rid:?int64"},
+		{"select u.id, r.id rid from user u join role r on u.id=r.user_id", "id:int64,rid:int64"},
+		// from中的子查询
+		{"select a.id, a.n from (select id, name n from user where id>?) a", "id:int64,n:string"},
+		{"select a.id, b.title from (select id from user) a left join (select user_id, title from role) b on a.id=b.user_id",
+			"id:int64,title:?string"},
+		{"(select id from user) union (select id from role)", "id:int64"},
+		// 聚合
+		{"select count(*) c, count(distinct id), min(id) mi, max(u.name) mx from user u", "c:int64,count(distinct id):int64,mi:?int64,mx:?string"},
+		// 指定的类型
+		{"select sum(score) total from user", "total:sql.NullFloat64"},
+	} {
+		columns, err := r.Query(c.sql)
+		if err != nil {
+			t.Fatal(c.sql, err)
+		}
+		if s := testColumns(columns); s != c.want {
+			t.Fatal(c.sql, s)
+		}
+	}
+}
+
+func TestResolveQueryError(t *testing.T) {
+	r := testResolver(t, nil)
+	for _, c := range []struct {
+		sql string
+		err string
+	}{
+		{"select id from user, role", "ambiguous column 'id'"},
+		{"select email from user", "unknown column 'email'"},
+		{"select u.email from user u", "unknown column 'u.email'"},
+		{"select r.id from user u", "unknown table 'r'"},
+		{"select r.* from user u", "unknown table 'r'"},
+		{"select id from users", "unknown table 'users'"},
+		{"select sum(score) total from user", "can not infer the type of 'total', set it in types"},
+		{"select max(id + 1) from user", "can not infer the type of 'max(id+1)', set it in types"},
+		{"update user set name=?", errOfflineSelect.Error()},
+	} {
+		_, err := r.Query(c.sql)
+		if err == nil || err.Error() != c.err {
+			t.Fatal(c.sql, err)
+		}
+	}
+}
+
+func TestResolveExec(t *testing.T) {
+	r := testResolver(t, nil)
+	for s, e := range map[string]string{
+		"insert into user(id,name) values(?,?)":                     "",
+		"insert ignore into `user`(`id`,score) values(?,?)":         "",
+		"replace into user(id,name) values(?,?)":                    "",
+		"insert into user(id) select id from role":                  "",
+		"insert into user(id,email) values(?,?)":                    "unknown column 'user.email'",
+		"insert into users(id) values(?)":                           "unknown table 'users'",
+		"update user set name=?, u.score=? where id=?":              "",
+		"update user u set u.name=? where u.id=?":                   "",
+		"update user set email=? where id=?":                        "unknown column 'user.email'",
+		"update users set name=?":                                   "unknown table 'users'",
+		"delete from role where id=?":                               "",
+		"delete from roles where id=?":                              "unknown table 'roles'",
+		"create table t(id int)":                                    "",
+		"update user u join role r on u.id=r.user_id set r.title=?": "",
+	} {
+		err := r.Exec(s)
+		if (e == "" && err != nil) || (e != "" && (err == nil || err.Error() != e)) {
+			t.Fatal(s, err)
+		}
+	}
+}
@@ -1,43 +1,41 @@
-package mysql
+package gen
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+)
 
 type sqlSegment struct {
-	string string
-	value  string
-	param  bool
-	column bool
+	string      string
+	value       string
+	param       bool
+	column      bool
+	placeholder string // 参数的占位符，比如"?"，"$1"
 }
 
 type sqlSegments []*sqlSegment
 
+// 生成拼接sql的代码，sql中可能有双引号的标识符，所以要转义
 func (ss sqlSegments) ToTPL() []string {
 	var s []string
 	var b strings.Builder
-	i := 0
-	for ; i < len(ss); i++ {
-		if ss[i].column {
-			b.WriteString(`" `)
-			s = append(s, b.String())
-			s = append(s, ss[i].string)
-			b.Reset()
-		} else {
-			if b.Len() < 1 {
-				b.WriteByte('"')
-			}
-			if ss[i].param {
-				b.WriteByte('?')
-			} else {
-				b.WriteString(ss[i].string)
+	for _, seg := range ss {
+		if seg.column {
+			if b.Len() > 0 {
+				s = append(s, strconv.Quote(b.String()))
+				b.Reset()
 			}
+			s = append(s, seg.string)
+			continue
+		}
+		if seg.param {
+			b.WriteString(seg.placeholder)
+		} else {
+			b.WriteString(seg.string)
 		}
 	}
 	if b.Len() > 0 {
-		seg := ss[len(ss)-1]
-		if !seg.column {
-			b.WriteByte('"')
-		}
-		s = append(s, b.String())
+		s = append(s, strconv.Quote(b.String()))
 	}
 	return s
 }
@@ -0,0 +1,278 @@
+package gen
+
+import "strings"
+
+type sqlTokenKind int
+
+const (
+	sqlTokenIdent  sqlTokenKind = iota // 标识符或者关键字
+	sqlTokenQuoted                     // `name`或者"name"
+	sqlTokenString                     // 'string'
+	sqlTokenNumber                     // 数字
+	sqlTokenParam                      // 参数的占位符，?或者$n
+	sqlTokenSymbol                     // 其他符号
+)
+
+// sql的词，标识符去掉了引号
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+}
+
+// 是否关键字，不区分大小写
+func (t *sqlToken) is(keyword string) bool {
+	return t.kind == sqlTokenIdent && strings.EqualFold(t.text, keyword)
+}
+
+func (t *sqlToken) isSymbol(symbol string) bool {
+	return t.kind == sqlTokenSymbol && t.text == symbol
+}
+
+// 是否可以作为表名，列名和别名
+func (t *sqlToken) isName() bool {
+	return t.kind == sqlTokenQuoted || (t.kind == sqlTokenIdent && !sqlKeywords[strings.ToLower(t.text)])
+}
+
+// 不能作为没有引号的名称的关键字
+var sqlKeywords = map[string]bool{
+	"select": true, "from": true, "where": true, "group": true, "order": true, "by": true, "having": true,
+	"limit": true, "offset": true, "union": true, "all": true, "distinct": true, "join": true, "inner": true,
+	"left": true, "right": true, "outer": true, "full": true, "cross": true, "natural": true, "straight_join": true,
+	"on": true, "using": true, "as": true, "and": true, "or": true, "not": true, "is": true, "null": true,
+	"in": true, "like": true, "between": true, "case": true, "when": true, "then": true, "else": true,
+	"end": true, "exists": true, "true": true, "false": true, "for": true, "window": true, "into": true,
+	"lock": true, "asc": true, "desc": true, "interval": true, "set": true, "values": true, "returning": true,
+	"fetch": true,
+}
+
+func isSQLDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isSQLIdent(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || isSQLDigit(c)
+}
+
+// 拆分sql，忽略注释
+func tokenizeSQL(s string, syntax Syntax) ([]*sqlToken, error) {
+	return tokenizeSQLEscape(s, syntax, syntax == MySQLSyntax)
+}
+
+// 拆分sql，backslash表示字符串中的\是否是转义符
+func tokenizeSQLEscape(s string, syntax Syntax, backslash bool) ([]*sqlToken, error) {
+	var tokens []*sqlToken
+	// 在mysql的/*!...*/中
+	execComment := false
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		// mysql的--后面要有空白，"1--1"是"1 - -1"
+		case c == '-' && i+1 < len(s) && s[i+1] == '-' && (syntax != MySQLSyntax || i+2 == len(s) || s[i+2] <= ' '),
+			c == '#' && syntax == MySQLSyntax:
+			j := strings.IndexByte(s[i:], '\n')
+			if j < 0 {
+				j = len(s) - i
+			}
+			i += j
+		case c == '/' && i+2 < len(s) && s[i+1] == '*' && s[i+2] == '!' && syntax == MySQLSyntax:
+			// 后面是版本号
+			i += 3
+			for i < len(s) && isSQLDigit(s[i]) {
+				i++
+			}
+			execComment = true
+		case c == '*' && i+1 < len(s) && s[i+1] == '/' && execComment:
+			execComment = false
+			i += 2
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			j := indexComment(s[i:], syntax == PostgresSyntax)
+			if j < 0 {
+				return nil, parseError(s[i:])
+			}
+			i += j
+		case c == '\'':
+			j := indexQuote(s[i:], backslash)
+			if j < 0 {
+				return nil, parseError(s[i:])
+			}
+			tokens = append(tokens, &sqlToken{kind: sqlTokenString, text: s[i+1 : i+j]})
+			i += j + 1
+		case (c == 'e' || c == 'E') && i+1 < len(s) && s[i+1] == '\'' && syntax == PostgresSyntax:
+			// E'...'中\是转义符
+			j := indexQuote(s[i+1:], true)
+			if j < 0 {
+				return nil, parseError(s[i:])
+			}
+			tokens = append(tokens, &sqlToken{kind: sqlTokenString, text: s[i+2 : i+1+j]})
+			i += j + 2
+		case c == '$' && syntax == PostgresSyntax && dollarTag(s[i:]) != "":
+			tag := dollarTag(s[i:])
+			j := strings.Index(s[i+len(tag):], tag)
+			if j < 0 {
+				return nil, parseError(s[i:])
+			}
+			tokens = append(tokens, &sqlToken{kind: sqlTokenString, text: s[i+len(tag) : i+len(tag)+j]})
+			i += len(tag) + j + len(tag)
+		case c == '`' || c == '"':
+			// mysql的"..."是字符串
+			j := indexQuote(s[i:], backslash && c == '"')
+			if j < 0 {
+				return nil, parseError(s[i:])
+			}
+			tokens = append(tokens, &sqlToken{kind: sqlTokenQuoted, text: s[i+1 : i+j]})
+			i += j + 1
+		case c == '?':
+			tokens = append(tokens, &sqlToken{kind: sqlTokenParam, text: "?"})
+			i++
+		case c == '$' && i+1 < len(s) && isSQLDigit(s[i+1]):
+			j := i + 1
+			for j < len(s) && isSQLDigit(s[j]) {
+				j++
+			}
+			tokens = append(tokens, &sqlToken{kind: sqlTokenParam, text: s[i:j]})
+			i = j
+		case isSQLDigit(c) || (c == '.' && i+1 < len(s) && isSQLDigit(s[i+1])):
+			j := i + 1
+			for j < len(s) && (isSQLDigit(s[j]) || s[j] == '.' || s[j] == 'e' || s[j] == 'E') {
+				j++
+			}
+			tokens = append(tokens, &sqlToken{kind: sqlTokenNumber, text: s[i:j]})
+			i = j
+		case isSQLIdent(c):
+			j := i + 1
+			for j < len(s) && isSQLIdent(s[j]) {
+				j++
+			}
+			tokens = append(tokens, &sqlToken{kind: sqlTokenIdent, text: s[i:j]})
+			i = j
+		default:
+			// 两个字符的运算符
+			if i+1 < len(s) {
+				switch s[i : i+2] {
+				case "<=", ">=", "<>", "!=", "||", "::", ":=":
+					tokens = append(tokens, &sqlToken{kind: sqlTokenSymbol, text: s[i : i+2]})
+					i += 2
+					continue
+				}
+			}
+			tokens = append(tokens, &sqlToken{kind: sqlTokenSymbol, text: string(c)})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// s以引号开始，返回对应的引号的位置，两个引号是一个引号，backslash表示\也是转义符
+func indexQuote(s string, backslash bool) int {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		if s[i] == '\\' && backslash {
+			i++
+		} else if s[i] == q {
+			if i+1 < len(s) && s[i+1] == q {
+				i++
+			} else {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// s以/*开始，返回注释的长度，nested表示注释可以嵌套
+func indexComment(s string, nested bool) int {
+	depth := 0
+	for i := 0; i+1 < len(s); i++ {
+		if s[i] == '/' && s[i+1] == '*' && (depth == 0 || nested) {
+			depth++
+			i++
+		} else if s[i] == '*' && s[i+1] == '/' {
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// postgres的$tag$，s不是以它开始返回空
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '$' {
+			return s[:i+1]
+		}
+		if !isSQLIdent(s[i]) || (i == 1 && isSQLDigit(s[i])) {
+			return ""
+		}
+	}
+	return ""
+}
+
+// 修改数据的关键字，后面是"("时是函数，比如mysql的replace()和insert()
+var sqlModifyKeywords = map[string]bool{
+	"insert": true, "update": true, "delete": true, "replace": true, "merge": true, "truncate": true, "call": true,
+}
+
+// 是否只是查询：select或者with ... select，不能有修改数据的语句（包括with中的），into和多条语句。
+// mysql的NO_BACKSLASH_ESCAPES和postgres的standard_conforming_strings会改变\的含义，两种解析都要是查询
+func isSelectSQL(s string, syntax Syntax) bool {
+	return isSelectTokens(tokenizeSQLEscape(s, syntax, false)) &&
+		(syntax == StandardSyntax || isSelectTokens(tokenizeSQLEscape(s, syntax, true)))
+}
+
+func isSelectTokens(tokens []*sqlToken, err error) bool {
+	if err != nil {
+		return false
+	}
+	tokens = trimSemicolon(tokens)
+	i := 0
+	for i < len(tokens) && tokens[i].isSymbol("(") {
+		i++
+	}
+	if i == len(tokens) || !(tokens[i].is("select") || tokens[i].is("with")) {
+		return false
+	}
+	for i, t := range tokens {
+		if t.isSymbol(";") || t.is("into") {
+			return false
+		}
+		if t.kind != sqlTokenIdent || !sqlModifyKeywords[strings.ToLower(t.text)] {
+			continue
+		}
+		// 函数，或者select ... for update
+		if i+1 < len(tokens) && tokens[i+1].isSymbol("(") {
+			continue
+		}
+		if t.is("update") && i > 0 && tokens[i-1].is("for") {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// 是否只有一条语句，不能解析时交给数据库
+func isSingleSQL(s string, syntax Syntax) bool {
+	tokens, err := tokenizeSQL(s, syntax)
+	if err != nil {
+		return true
+	}
+	for _, t := range trimSemicolon(tokens) {
+		if t.isSymbol(";") {
+			return false
+		}
+	}
+	return true
+}
+
+// 去掉最后的;
+func trimSemicolon(tokens []*sqlToken) []*sqlToken {
+	for len(tokens) > 0 && tokens[len(tokens)-1].isSymbol(";") {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return tokens
+}
@@ -0,0 +1,60 @@
+package gen
+
+import "testing"
+
+func TestIsSingleSQL(t *testing.T) {
+	for s, ok := range map[string]bool{
+		"select * from user;;":                          true,
+		"select ';' from user":                          true,
+		"select id from user; commit; delete from user": false,
+		`select 'a\\'; delete from user`:                false,
+		`select 'a\'; delete from user'`:                true,
+	} {
+		if isSingleSQL(s, MySQLSyntax) != ok {
+			t.Fatal(s)
+		}
+	}
+}
+
+func TestIsSelectSQL(t *testing.T) {
+	for s, ok := range map[string]bool{
+		"select * from user": true,
+		"(select id from user) union (select id from role);":           true,
+		"with a as (select id from user) select * from a":              true,
+		"select replace(name,'a','b'), insert(name,1,1,'x') from user": true,
+		"select * from user where id=? for update":                     true,
+		"select 'delete' from user":                                    true,
+		"delete from user":                                             false,
+		"with a as (select 1) delete from user":                        false,
+		"with a as (delete from user returning id) select * from a":    false,
+		"select * from user into outfile '/tmp/user'":                  false,
+		"select id from user; delete from user":                        false,
+		"show tables":                                                  false,
+	} {
+		if isSelectSQL(s, MySQLSyntax) != ok {
+			t.Fatal(s)
+		}
+	}
+	for _, c := range []struct {
+		sql      string
+		mysql    bool
+		postgres bool
+	}{
+		// postgres的\不是转义符
+		{`select 'a\'; commit; delete from users; -- \' '`, false, false},
+		{`select 'it''s' from user`, true, true},
+		{`select e'\';delete' from user`, false, true},
+		{`select $$;delete from user$$, $a$ ' $a$; commit; --'`, false, false},
+		{`select $a$;$a$ from user`, false, true},
+		// postgres的#是运算符
+		{"select 1 # 1; delete from user", true, false},
+		{"select 1 /* /* */ ; delete from user */", false, true},
+		{"select 1--1 into outfile '/tmp/user'", false, true},
+		{"select * from user /*!50000 into outfile '/tmp/user' */", false, true},
+		{`select "a\"; delete from user" from user`, false, false},
+	} {
+		if isSelectSQL(c.sql, MySQLSyntax) != c.mysql || isSelectSQL(c.sql, PostgresSyntax) != c.postgres {
+			t.Fatal(c.sql)
+		}
+	}
+}
@@ -1,7 +1,6 @@
-package mysql
+package gen
 
 import (
-	"database/sql"
 	"io"
 	"strings"
 )
@@ -55,11 +54,21 @@ type scanTPL struct {
 	NullType  string
 	NullValue string
 	NullType2 string // 强制转换的类型
+	Func      string // 包装Scan的参数的函数
+}
+
+// Scan的参数，v是变量
+func (t *scanTPL) Dest(v string) string {
+	if t.Func == "" {
+		return "&" + v
+	}
+	return t.Func + "(&" + v + ")"
 }
 
 type queryTPL struct {
 	tpl
-	NullField bool // 字段是否sql.NullXxx
+	NullField bool   // 字段是否sql.NullXxx
+	ScanFunc  string // ScanBackend.ScanFunc
 }
 
 type querySqlTPL struct {
@@ -105,11 +114,11 @@ type queryStructTPL struct {
 	Scan  []*scanTPL
 }
 
-func (t *queryStructTPL) InitFieldAndScan(columns []*sql.ColumnType) {
+func (t *queryStructTPL) InitFieldAndScan(columns []*Column) {
 	nullable := false
 	for _, c := range columns {
 		t.Field = append(t.Field, dbColumnToField(c, t.NullField))
-		scan := dbColumnToScanTPL(c, t.NullField)
+		scan := dbColumnToScanTPL(c, t.NullField, t.ScanFunc)
 		t.Scan = append(t.Scan, scan)
 		if !nullable && scan.NullType != "" {
 			nullable = true
@@ -124,11 +133,11 @@ type querySqlStructTPL struct {
 	Scan  []*scanTPL
 }
 
-func (t *querySqlStructTPL) InitFieldAndScan(columns []*sql.ColumnType) {
+func (t *querySqlStructTPL) InitFieldAndScan(columns []*Column) {
 	nullable := false
 	for _, c := range columns {
 		t.Field = append(t.Field, dbColumnToField(c, t.NullField))
-		scan := dbColumnToScanTPL(c, t.NullField)
+		scan := dbColumnToScanTPL(c, t.NullField, t.ScanFunc)
 		t.Scan = append(t.Scan, scan)
 		if !nullable && scan.NullType != "" {
 			nullable = true
@@ -5,30 +5,37 @@ import (
 	"flag"
 	"fmt"
 	"github.com/qq51529210/db/db2go"
-	"github.com/qq51529210/db/sql2go/mysql"
+	"github.com/qq51529210/db/sql2go/gen"
+	_ "github.com/qq51529210/db/sql2go/mysql"
+	_ "github.com/qq51529210/db/sql2go/postgres"
+	_ "github.com/qq51529210/db/sql2go/sqlite"
 	"github.com/qq51529210/log"
-	"net/url"
 	"os"
-	"path"
 	"path/filepath"
 	"strings"
 )
 
 type cfg struct {
-	DBUrl  string      `json:"dbUrl"`           // 数据库配置
-	Pkg    string      `json:"pkg,omitempy"`    // 代码包名，空则使用数据库名称
-	File   string      `json:"file,omitempy"`   // 生成代码根目录，空则使用程序当前目录
-	Query  []*cfgQuery `json:"query,omitempy"`  // 函数
-	Exec   []*cfgExec  `json:"exec,omitempy"`   // 函数
-	Driver string      `json:"driver,omitempy"` // 数据库驱动
+	DBUrl        string      `json:"dbUrl"`                 // 数据库配置
+	Pkg          string      `json:"pkg,omitempy"`          // 代码包名，空则使用数据库名称
+	File         string      `json:"file,omitempy"`         // 生成代码根目录，空则使用程序当前目录
+	Query        []*cfgQuery `json:"query,omitempy"`        // 函数
+	Exec         []*cfgExec  `json:"exec,omitempy"`         // 函数
+	Driver       string      `json:"driver,omitempy"`       // 数据库驱动包，空则使用默认的
+	DriverName   string      `json:"driverName,omitempy"`   // 生成的Init中sql.Open的驱动名称，空则使用默认的
+	Explain      int64       `json:"explain,omitempy"`      // 分析query的执行计划，表的估算行数超过这个值时警告全表扫描等问题，0不分析
+	PasswordFile string      `json:"passwordFile,omitempy"` // 密码文件，设置了则使用文件内容作为连接密码
+	Schema       string      `json:"schema,omitempy"`       // db2go保存的数据库结构json，设置了则离线生成，不连接数据库
+	selectOnly   bool        // query只能是select，http请求的配置
 }
 
 type cfgQuery struct {
-	Name string   `json:"func,omitempy"` //
-	Tx   string   `json:"tx,omitempy"`   //
-	Row  bool     `json:"row,omitempy"`  //
-	Null bool     `json:"null,omitempy"` //
-	SQL  []string `json:"sql,omitempy"`  //
+	Name  string            `json:"func,omitempy"`  //
+	Tx    string            `json:"tx,omitempy"`    //
+	Row   bool              `json:"row,omitempy"`   //
+	Null  bool              `json:"null,omitempy"`  //
+	SQL   []string          `json:"sql,omitempy"`   //
+	Types map[string]string `json:"types,omitempy"` // 结果集的列（或别名）的go类型
 }
 
 type cfgExec struct {
@@ -39,15 +46,19 @@ type cfgExec struct {
 
 func main() {
 	log.Recover(nil)
-	var config, http string
-	flag.StringVar(&config, "config", "", "config file path")
+	var config, http, hosts string
+	var defaultDB bool
+	flag.StringVar(&config, "config", "", "config file path, with -http it is the default config of requests")
 	flag.StringVar(&http, "http", "", "http listen address")
+	flag.BoolVar(&defaultDB, "defaultdb", false, "with -http, requests without dbUrl use the database of -config")
+	flag.StringVar(&hosts, "hosts", "", "with -http, hosts that dbUrl of requests can connect to, like db1,db2:3306, empty means requests can not set dbUrl")
 	flag.Parse()
-	if config != "" {
-		genCode(config)
+	if http != "" {
+		checkError(serve(http, config, hosts, defaultDB))
 		return
 	}
-	if http != "" {
+	if config != "" {
+		checkError(genCode(config))
 		return
 	}
 	flag.PrintDefaults()
@@ -59,61 +70,164 @@ func checkError(err error) {
 	}
 }
 
-func loadCfg(path string) *cfg {
+func loadCfg(path string) (*cfg, error) {
 	f, err := os.Open(path)
-	checkError(err)
+	if err != nil {
+		return nil, err
+	}
 	defer func() {
 		_ = f.Close()
 	}()
 	c := new(cfg)
 	err = json.NewDecoder(f).Decode(c)
-	checkError(err)
-	return c
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// 生成代码的错误，Section是config，query或者exec，Index是函数在数组中的位置
+type genError struct {
+	Section string `json:"section"`
+	Index   int    `json:"index"`
+	Func    string `json:"func,omitempty"`
+	Message string `json:"message"`
 }
 
-func genCode(config string) {
-	c := loadCfg(config)
-	_url, err := url.Parse(c.DBUrl)
-	checkError(err)
-	dbUrl := strings.Replace(c.DBUrl, _url.Scheme+"://", "", 1)
-	switch strings.ToLower(_url.Scheme) {
-	case db2go.MYSQL:
-		// 包名
-		pkg := c.Pkg
-		if pkg == "" {
-			_, pkg = path.Split(_url.Path)
+func (e *genError) Error() string {
+	if e.Section == "config" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s[%d]: %s", e.Section, e.Index, e.Message)
+}
+
+func configError(err error) error {
+	return &genError{Section: "config", Message: err.Error()}
+}
+
+// 按配置测试sql，生成代码，c.Pkg为空时设置为数据库名称，返回的Code需要Close
+func newCode(c *cfg) (*gen.Code, error) {
+	var code *gen.Code
+	var err error
+	if c.Schema != "" {
+		code, err = openSchemaCode(c)
+	} else {
+		code, err = openCode(c)
+	}
+	if err != nil {
+		return nil, configError(err)
+	}
+	code.SetDriverName(c.DriverName)
+	code.SetSelectOnly(c.selectOnly)
+	// 执行计划
+	code.SetExplain(c.Explain)
+	// sql生成FuncTPL
+	for i, f := range c.Query {
+		_, err = code.QueryTypes(strings.Join(f.SQL, " "), f.Name, f.Tx, f.Row, f.Null, f.Types)
+		if err != nil {
+			_ = code.Close()
+			return nil, &genError{Section: "query", Index: i, Func: f.Name, Message: err.Error()}
 		}
-		// 驱动
-		driver := c.Driver
-		if driver == "" {
-			driver = "github.com/go-sql-driver/mysql"
+	}
+	for i, f := range c.Exec {
+		_, err = code.Exec(strings.Join(f.SQL, " "), f.Name, f.Tx)
+		if err != nil {
+			_ = code.Close()
+			return nil, &genError{Section: "exec", Index: i, Func: f.Name, Message: err.Error()}
 		}
-		// 生成路径
-		file := c.File
-		if file == "" {
-			file = pkg + ".go"
+	}
+	return code, nil
+}
+
+// 连接数据库测试sql
+func openCode(c *cfg) (*gen.Code, error) {
+	// 解析连接字符串，支持${ENV}，错误信息不会有密码
+	dsn, err := db2go.ParseURL(c.DBUrl)
+	if err != nil {
+		return nil, err
+	}
+	if c.PasswordFile != "" {
+		err = dsn.SetPasswordFile(c.PasswordFile)
+		if err != nil {
+			return nil, err
 		}
-		if filepath.Ext(file) == "" {
-			file += ".go"
+	}
+	backend, err := getBackend(dsn.DBType())
+	if err != nil {
+		return nil, err
+	}
+	// 包名
+	if c.Pkg == "" {
+		c.Pkg = dsn.Name()
+	}
+	return gen.NewCode(backend, c.Pkg, c.Driver, dsn.String())
+}
+
+// 使用数据库结构离线生成
+func openSchemaCode(c *cfg) (*gen.Code, error) {
+	var schema *db2go.Schema
+	if strings.HasSuffix(strings.ToLower(c.Schema), ".go") {
+		// go的结构体
+		s, err := db2go.ReadSchemaGo(c.Schema, nil)
+		if err != nil {
+			return nil, err
 		}
-		code, err := mysql.NewCode(pkg, driver, dbUrl)
-		checkError(err)
-		// sql生成FuncTPL
-		for i, f := range c.Query {
-			_, err = code.Query(strings.Join(f.SQL, " "), f.Name, f.Tx, f.Row, f.Null)
-			if err != nil {
-				checkError(fmt.Errorf("query[%d]: %v", i, err))
-			}
+		schema = s
+	} else {
+		f, err := os.Open(c.Schema)
+		if err != nil {
+			return nil, err
 		}
-		for i, f := range c.Exec {
-			_, err = code.Exec(strings.Join(f.SQL, " "), f.Name, f.Tx)
-			if err != nil {
-				checkError(fmt.Errorf("exec[%d]: %v", i, err))
-			}
+		defer func() {
+			_ = f.Close()
+		}()
+		schema, err = db2go.ReadSchemaJSON(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", c.Schema, err)
 		}
-		// 保存
-		checkError(code.SaveFile(file))
-	default:
-		panic(fmt.Errorf("unsupported database '%s'", _url.Scheme))
 	}
+	backend, err := getBackend(schema.DBType())
+	if err != nil {
+		return nil, err
+	}
+	// 包名
+	if c.Pkg == "" {
+		c.Pkg = schema.Name()
+	}
+	return gen.NewCodeSchema(backend, c.Pkg, c.Driver, schema)
+}
+
+func getBackend(dbType string) (gen.Backend, error) {
+	backend := gen.GetBackend(dbType)
+	if backend == nil {
+		return nil, fmt.Errorf("unsupported database '%s', supported %s", dbType, strings.Join(gen.Backends(), ", "))
+	}
+	return backend, nil
+}
+
+func genCode(config string) error {
+	c, err := loadCfg(config)
+	if err != nil {
+		return err
+	}
+	code, err := newCode(c)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = code.Close()
+	}()
+	for _, w := range code.Warnings() {
+		_, _ = fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+	// 生成路径
+	file := c.File
+	if file == "" {
+		file = c.Pkg + ".go"
+	}
+	if filepath.Ext(file) == "" {
+		file += ".go"
+	}
+	// 保存
+	return code.SaveFile(file)
 }
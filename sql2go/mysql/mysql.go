@@ -0,0 +1,131 @@
+package mysql
+
+import (
+	"database/sql"
+	"github.com/qq51529210/db/db2go"
+	"github.com/qq51529210/db/sql2go/gen"
+	"strings"
+)
+
+func init() {
+	gen.Register(db2go.MYSQL, Backend{})
+}
+
+// mysql的代码生成
+type Backend struct{}
+
+func (Backend) DriverPkg() string {
+	return db2go.DriverPkg(db2go.MYSQL)
+}
+
+func (Backend) DriverName() string {
+	return "mysql"
+}
+
+func (Backend) DBType() string {
+	return db2go.MYSQL
+}
+
+func (Backend) Open(dbUrl string) (*sql.DB, error) {
+	return sql.Open("mysql", dbUrl)
+}
+
+func (Backend) Placeholder(int) string {
+	return "?"
+}
+
+func (Backend) ReuseParam() bool {
+	return false
+}
+
+func (Backend) Syntax() gen.Syntax {
+	return gen.MySQLSyntax
+}
+
+// mysql会转换字符串，直接使用类型的名称
+func (Backend) TestValue(typ string) interface{} {
+	return typ
+}
+
+func (Backend) Columns(rows *sql.Rows) ([]*gen.Column, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	var columns []*gen.Column
+	for _, t := range types {
+		na, ok := t.Nullable()
+		columns = append(columns, &gen.Column{Name: t.Name(), DatabaseType: t.DatabaseTypeName(), Nullable: ok && na})
+	}
+	return columns, nil
+}
+
+func (Backend) GoType(dataType string) string {
+	dataType = strings.ToLower(dataType)
+	switch dataType {
+	case "tinyint":
+		return "int8"
+	case "smallint":
+		return "int16"
+	case "mediumint":
+		return "int32"
+	case "int":
+		return "int"
+	case "bigint":
+		return "int64"
+	case "tinyint unsigned":
+		return "uint8"
+	case "smallint unsigned":
+		return "uint16"
+	case "mediumint unsigned":
+		return "uint32"
+	case "int unsigned":
+		return "uint"
+	case "bigint unsigned":
+		return "uint64"
+	case "float":
+		return "float32"
+	case "double", "decimal":
+		return "float64"
+	case "tinyblob", "blob", "mediumblob", "longblob":
+		return "[]byte"
+	case "tinytext", "text", "mediumtext", "longtext":
+		return "string"
+	case "year":
+		return "uint16"
+	case "date", "time", "datetime", "timestamp":
+		return "string"
+	default:
+		if strings.HasPrefix(dataType, "binary") {
+			return "[]byte"
+		}
+		if strings.HasPrefix(dataType, "decimal") {
+			return "float64"
+		}
+		return "string"
+	}
+}
+
+// 去掉长度和zerofill，比如"int(11) unsigned zerofill"是"int unsigned"
+func (b Backend) DeclaredGoType(columnType string) string {
+	typ := strings.ToLower(columnType)
+	if i := strings.IndexByte(typ, '('); i >= 0 {
+		if j := strings.LastIndexByte(typ, ')'); j > i {
+			typ = typ[:i] + typ[j+1:]
+		}
+	}
+	typ = strings.Join(strings.Fields(strings.ReplaceAll(typ, "zerofill", "")), " ")
+	return b.GoType(typ)
+}
+
+func (Backend) ErrorHelper(driverPkg string) string {
+	if driverPkg != "github.com/go-sql-driver/mysql" {
+		return ""
+	}
+	return `func IsUniqueKeyError(err error) bool {
+	if e, o := err.(*mysql.MySQLError); o {
+		return e.Number == 1169
+	}
+	return false
+}`
+}
@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"database/sql"
+	_ "github.com/lib/pq"
+	"github.com/qq51529210/db/db2go"
+	"github.com/qq51529210/db/sql2go/gen"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 还不能读取postgres的结构，不生成CheckSchema，不分析执行计划
+func init() {
+	gen.Register(db2go.POSTGRES, Backend{})
+}
+
+// postgres的代码生成
+type Backend struct{}
+
+func (Backend) DriverPkg() string {
+	return db2go.DriverPkg(db2go.POSTGRES)
+}
+
+func (Backend) DriverName() string {
+	return "postgres"
+}
+
+func (Backend) Open(dbUrl string) (*sql.DB, error) {
+	return sql.Open("postgres", dbUrl)
+}
+
+func (Backend) Placeholder(index int) string {
+	return "$" + strconv.Itoa(index)
+}
+
+func (Backend) ReuseParam() bool {
+	return true
+}
+
+func (Backend) Syntax() gen.Syntax {
+	return gen.PostgresSyntax
+}
+
+// postgres不会转换参数的类型，使用类型的零值
+func (Backend) TestValue(typ string) interface{} {
+	switch typ {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return 0
+	case "float32", "float64":
+		return 0.0
+	case "bool":
+		return false
+	case "[]byte":
+		return []byte{}
+	case "time.Time":
+		return time.Time{}
+	case "nstring", "nint", "nfloat":
+		return nil
+	default:
+		return ""
+	}
+}
+
+// lib/pq不返回列是否可以为NULL，当作可以为NULL
+func (Backend) Columns(rows *sql.Rows) ([]*gen.Column, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	var columns []*gen.Column
+	for _, t := range types {
+		na, ok := t.Nullable()
+		columns = append(columns, &gen.Column{Name: t.Name(), DatabaseType: t.DatabaseTypeName(), Nullable: !ok || na})
+	}
+	return columns, nil
+}
+
+// lib/pq的DatabaseTypeName是大写的类型名，数组以_开头
+func (Backend) GoType(dataType string) string {
+	dataType = strings.ToUpper(dataType)
+	switch dataType {
+	case "INT2":
+		return "int16"
+	case "INT4":
+		return "int32"
+	case "INT8":
+		return "int64"
+	case "FLOAT4":
+		return "float32"
+	case "FLOAT8", "NUMERIC":
+		return "float64"
+	case "BOOL":
+		return "bool"
+	case "BYTEA":
+		return "[]byte"
+	case "DATE", "TIMESTAMP", "TIMESTAMPTZ":
+		return "time.Time"
+	default:
+		// TEXT，VARCHAR，BPCHAR，UUID，JSON，TIME，数组等
+		return "string"
+	}
+}
+
+// 声明的类型对应的lib/pq的类型名
+var declaredTypes = map[string]string{
+	"smallint": "INT2", "int2": "INT2", "smallserial": "INT2", "serial2": "INT2",
+	"integer": "INT4", "int": "INT4", "int4": "INT4", "serial": "INT4", "serial4": "INT4",
+	"bigint": "INT8", "int8": "INT8", "bigserial": "INT8", "serial8": "INT8",
+	"real": "FLOAT4", "float4": "FLOAT4",
+	"double precision": "FLOAT8", "float8": "FLOAT8", "float": "FLOAT8",
+	"numeric": "NUMERIC", "decimal": "NUMERIC",
+	"boolean": "BOOL", "bool": "BOOL",
+	"bytea": "BYTEA", "date": "DATE",
+	"timestamp": "TIMESTAMP", "timestamp without time zone": "TIMESTAMP",
+	"timestamptz": "TIMESTAMPTZ", "timestamp with time zone": "TIMESTAMPTZ",
+}
+
+// 去掉长度和精度，比如"numeric(10,2)"，"timestamp(6) with time zone"，数组是string
+func (b Backend) DeclaredGoType(columnType string) string {
+	typ := strings.ToLower(columnType)
+	if strings.HasSuffix(typ, "]") {
+		return "string"
+	}
+	if i := strings.IndexByte(typ, '('); i >= 0 {
+		if j := strings.IndexByte(typ[i:], ')'); j >= 0 {
+			typ = typ[:i] + typ[i+j+1:]
+		}
+	}
+	return b.GoType(declaredTypes[strings.Join(strings.Fields(typ), " ")])
+}
+
+func (Backend) ErrorHelper(driverPkg string) string {
+	if driverPkg != "github.com/lib/pq" {
+		return ""
+	}
+	return `func IsUniqueKeyError(err error) bool {
+	if e, o := err.(*pq.Error); o {
+		return e.Code == "23505"
+	}
+	return false
+}`
+}
@@ -0,0 +1,26 @@
+package postgres
+
+import "testing"
+
+func TestDeclaredGoType(t *testing.T) {
+	for typ, goType := range map[string]string{
+		"bigint":                      "int64",
+		"BIGSERIAL":                   "int64",
+		"integer":                     "int32",
+		"smallint":                    "int16",
+		"boolean":                     "bool",
+		"double precision":            "float64",
+		"numeric(10,2)":               "float64",
+		"real":                        "float32",
+		"bytea":                       "[]byte",
+		"timestamp(6) with time zone": "time.Time",
+		"date":                        "time.Time",
+		"character varying(32)":       "string",
+		"integer[]":                   "string",
+		"uuid":                        "string",
+	} {
+		if s := (Backend{}).DeclaredGoType(typ); s != goType {
+			t.Fatal(typ, s)
+		}
+	}
+}
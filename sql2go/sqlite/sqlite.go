@@ -0,0 +1,162 @@
+package sqlite
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/qq51529210/db/db2go"
+	"github.com/qq51529210/db/sql2go/gen"
+	"os"
+	"strings"
+)
+
+// 还不能读取sqlite的结构，不生成CheckSchema，不分析执行计划
+func init() {
+	gen.Register(db2go.SQLITE, Backend{})
+}
+
+// sqlite的代码生成
+type Backend struct{}
+
+func (Backend) DriverPkg() string {
+	return db2go.DriverPkg(db2go.SQLITE)
+}
+
+func (Backend) DriverName() string {
+	return "sqlite3"
+}
+
+// 文件必须存在，不存在时go-sqlite3会创建一个空的数据库
+func (Backend) Open(dbUrl string) (*sql.DB, error) {
+	file := strings.TrimPrefix(dbUrl, "file:")
+	if i := strings.IndexByte(file, '?'); i >= 0 {
+		file = file[:i]
+	}
+	if _, err := os.Stat(file); err != nil {
+		return nil, err
+	}
+	return sql.Open("sqlite3", dbUrl)
+}
+
+func (Backend) Placeholder(int) string {
+	return "?"
+}
+
+func (Backend) ReuseParam() bool {
+	return false
+}
+
+func (Backend) Syntax() gen.Syntax {
+	return gen.StandardSyntax
+}
+
+// 类型的零值，不能使用类型的名称，否则id>'int64'这样的条件没有行
+func (Backend) TestValue(typ string) interface{} {
+	switch typ {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return 0
+	case "float32", "float64":
+		return 0.0
+	case "bool":
+		return false
+	case "[]byte":
+		return []byte{}
+	case "nstring", "nint", "nfloat":
+		return nil
+	default:
+		return ""
+	}
+}
+
+// 值的类型对应的sqlite类型
+func valueType(v interface{}) string {
+	switch v.(type) {
+	case int64:
+		return "INTEGER"
+	case float64:
+		return "REAL"
+	case []byte:
+		return "BLOB"
+	case bool:
+		return "BOOLEAN"
+	default:
+		return "TEXT"
+	}
+}
+
+// 表达式的列没有声明的类型，使用第一行的值的类型，没有行或者是NULL时不能推断，
+// 比如空表的sum()，类型为空，需要在types中指定。go-sqlite3不返回列是否可以为NULL，当作可以为NULL
+func (Backend) Columns(rows *sql.Rows) ([]*gen.Column, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	var values []interface{}
+	if rows.Next() {
+		values = make([]interface{}, len(types))
+		scans := make([]interface{}, len(types))
+		for i := range values {
+			scans[i] = &values[i]
+		}
+		err = rows.Scan(scans...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var columns []*gen.Column
+	for i, t := range types {
+		c := &gen.Column{Name: t.Name(), DatabaseType: t.DatabaseTypeName(), Nullable: true}
+		if c.DatabaseType == "" && values != nil && values[i] != nil {
+			c.DatabaseType = valueType(values[i])
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// 按sqlite的类型亲和性规则，dataType是声明的类型，比如"VARCHAR(32)"，
+// 日期和时间按文本保存，使用string，没有类型时不能推断，返回空
+func (Backend) GoType(dataType string) string {
+	dataType = strings.ToUpper(dataType)
+	switch {
+	case dataType == "":
+		return ""
+	case strings.Contains(dataType, "BOOL"):
+		return "bool"
+	case strings.Contains(dataType, "INT"):
+		return "int64"
+	case strings.Contains(dataType, "CHAR"), strings.Contains(dataType, "CLOB"), strings.Contains(dataType, "TEXT"):
+		return "string"
+	case strings.Contains(dataType, "BLOB"):
+		return "[]byte"
+	case strings.Contains(dataType, "REAL"), strings.Contains(dataType, "FLOA"), strings.Contains(dataType, "DOUB"),
+		strings.Contains(dataType, "NUMERIC"), strings.Contains(dataType, "DECIMAL"):
+		return "float64"
+	default:
+		return "string"
+	}
+}
+
+// 声明的类型和查询返回的一样，按亲和性规则，没有声明类型的列可以保存任何值，使用string
+func (b Backend) DeclaredGoType(columnType string) string {
+	if columnType == "" {
+		return "string"
+	}
+	return b.GoType(columnType)
+}
+
+// sqlite是弱类型，列中的值可以和声明的类型不同，生成的代码按Scan的参数的类型转换
+func (Backend) ScanFunc() string {
+	return "db2go.SQLiteScan"
+}
+
+func (Backend) ErrorHelper(driverPkg string) string {
+	if driverPkg != "github.com/mattn/go-sqlite3" {
+		return ""
+	}
+	return `func IsUniqueKeyError(err error) bool {
+	if e, o := err.(sqlite3.Error); o {
+		return e.ExtendedCode == sqlite3.ErrConstraintUnique || e.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	return false
+}`
+}
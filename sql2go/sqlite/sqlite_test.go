@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"bytes"
+	"database/sql"
+	"github.com/qq51529210/db/sql2go/gen"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoType(t *testing.T) {
+	for typ, goType := range map[string]string{
+		"INTEGER":     "int64",
+		"bigint":      "int64",
+		"BOOLEAN":     "bool",
+		"VARCHAR(32)": "string",
+		"text":        "string",
+		"BLOB":        "[]byte",
+		"REAL":        "float64",
+		"double":      "float64",
+		"DECIMAL(10)": "float64",
+		"DATETIME":    "string",
+		"":            "",
+	} {
+		if s := (Backend{}).GoType(typ); s != goType {
+			t.Fatal(typ, s)
+		}
+	}
+	if s := (Backend{}).DeclaredGoType(""); s != "string" {
+		t.Fatal(s)
+	}
+}
+
+// 数据库文件，user表有一行
+func testDB(t *testing.T) string {
+	file := filepath.Join(t.TempDir(), "app.db")
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	_, err = db.Exec(`create table user(id integer primary key, name varchar(32), score real);
+create table log(id integer);
+insert into user values(1, 'a', '1.5')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestOpen(t *testing.T) {
+	file := testDB(t)
+	db, err := (Backend{}).Open(file + "?_fk=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+	// 不创建文件
+	_, err = (Backend{}).Open(filepath.Join(filepath.Dir(file), "none.db"))
+	if err == nil {
+		t.FailNow()
+	}
+}
+
+// 结果集的列，name:type，类型不能推断时是?
+func testColumns(t *testing.T, db *sql.DB, query string, args ...interface{}) string {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	columns, err := (Backend{}).Columns(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s []string
+	for _, c := range columns {
+		typ := (Backend{}).GoType(c.DatabaseType)
+		if typ == "" {
+			typ = "?"
+		}
+		s = append(s, c.Name+":"+typ)
+	}
+	return strings.Join(s, ",")
+}
+
+func TestColumns(t *testing.T) {
+	db, err := (Backend{}).Open(testDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	// 参数是类型的零值，条件有行
+	q := "select id, name, sum(id) total, avg(score) s, count(*) c, 'x' || name str from user where id>?"
+	if s := testColumns(t, db, q, (Backend{}).TestValue("int64")); s != "id:int64,name:string,total:int64,s:float64,c:int64,str:string" {
+		t.Fatal(s)
+	}
+	// 没有行，表达式不能推断类型
+	if s := testColumns(t, db, "select id, sum(id) total, count(*) c from log"); s != "id:int64,total:?,c:int64" {
+		t.Fatal(s)
+	}
+}
+
+func TestScanFunc(t *testing.T) {
+	file := testDB(t)
+	code, err := gen.NewCode(Backend{}, "dao", "", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = code.Close()
+	}()
+	_, err = code.QueryTypes("select id, name, score from user where id>{id:int64}", "UserList", "", false, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = code.QueryTypes("select sum(id) total from log", "LogTotal", "", true, false, nil)
+	if err == nil || err.Error() != "can not infer the type of 'total', set it in types" {
+		t.Fatal(err)
+	}
+	_, err = code.QueryTypes("select sum(id) total from log", "LogTotal", "", true, false, map[string]string{"total": "int64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = code.Write(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	if !strings.Contains(s, `"github.com/qq51529210/db/db2go"`) ||
+		!strings.Contains(s, "db2go.SQLiteScan(&model.Name),") ||
+		!strings.Contains(s, "db2go.SQLiteScan(&model.Id),") ||
+		!strings.Contains(s, ").Scan(db2go.SQLiteScan(&model))") {
+		t.Fatal(s)
+	}
+}